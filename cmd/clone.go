@@ -4,48 +4,63 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 
-	"github.com/jonasbn/baseline/internal/sources/bitbucket"
-	"github.com/jonasbn/baseline/internal/sources/github"
+	"github.com/jonasbn/baseline/internal/catalog"
 	"github.com/jonasbn/baseline/internal/types"
 	"github.com/jonasbn/baseline/internal/worker"
 	"github.com/spf13/cobra"
 )
 
 var (
-	useSSH bool
+	useSSH            bool
+	cloneDepth        int
+	cloneShallowSince string
+	cloneFilter       string
+	cloneStrategies   []string
 )
 
 // cloneCmd represents the clone command
 var cloneCmd = &cobra.Command{
 	Use:   "clone",
 	Short: "Clone repositories from the specified source into the target directory",
-	Long: `Clone repositories from the specified source (GitHub or Bitbucket) into the target 
+	Long: `Clone repositories from the specified source (GitHub, Bitbucket, GitLab, Gitea or Azure DevOps) into the target 
 directory and update existing ones.
 
-This command fetches all repositories from the organization and clones them as bare 
-repositories, setting read-only permissions for searching purposes.
+This command fetches all repositories from the organization and clones them, setting
+read-only permissions for searching purposes. With --mode mirror every branch and tag
+is stored in a bare mirror instead of a working tree of the default branch.
 
 Use the --ssh flag to clone using SSH URLs instead of HTTPS URLs, which is useful 
-when you have SSH keys configured and want to avoid HTTPS authentication issues.`,
+when you have SSH keys configured and want to avoid HTTPS authentication issues.
+
+Use the --target flag, repeatedly, to clone from several sources and organizations
+in one run, e.g. --target github:acme --target bitbucket:widgets.
+
+Huge repositories can be cloned shallow with --depth or --shallow-since, or as partial
+clones with --filter blob:none (blobless) or --filter tree:0 (treeless). Use --strategy,
+repeatedly, to select options per repository, e.g. --strategy 'acme/monorepo:depth=1'.
+Shallow repositories stay shallow when they are updated.
+
+Use --sparse, repeatedly, to check out only some directories, or --sparse-rule to select
+them per repository, e.g. --sparse-rule 'acme/monorepo:src,docs'.
+
+Use --submodules to initialize submodules recursively. Repositories whose submodules
+fail are still cloned, the failures are reported separately.
+
+Use --lfs to select how Git LFS content is handled: skip (never run git-lfs), pointers
+(keep the pointer files), fetch (download all content) or fetch-include=<glob>.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
 
-		// Create the appropriate source client
-		var sourceClient types.RepositorySource
-
-		switch source {
-		case "github":
-			sourceClient = github.NewGitHubClient(githubToken)
-		case "bitbucket":
-			sourceClient = bitbucket.NewBitbucketClient(bitbucketUser, bitbucketToken, verbose)
-		default:
-			return fmt.Errorf("unsupported source: %s (supported: github, bitbucket)", source)
+		targets, err := resolveTargets()
+		if err != nil {
+			return err
 		}
 
 		if verbose {
-			fmt.Printf("Cloning repositories from %s for organization: %s\n", source, organization)
+			for _, t := range targets {
+				fmt.Printf("Cloning repositories from %s for organization: %s\n", t.Source, t.Organization)
+			}
 			fmt.Printf("Target directory: %s\n", directory)
 			fmt.Printf("Using %d concurrent threads\n", threads)
 			if useSSH {
@@ -60,60 +75,127 @@ when you have SSH keys configured and want to avoid HTTPS authentication issues.
 			return fmt.Errorf("failed to create target directory %s: %w", directory, err)
 		}
 
-		// Fetch repositories
-		repositories, err := sourceClient.GetRepositories(ctx, organization)
+		// Validate the Git options and filters before contacting any source
+		gitOptions, err := newGitOptions()
+		if err != nil {
+			return err
+		}
+		repoFilter, err := newFilter()
+		if err != nil {
+			return err
+		}
+
+		// Fetch repositories from all targets
+		fetched, err := fetchTargets(ctx, targets)
 		if err != nil {
-			return fmt.Errorf("failed to fetch repositories: %w", err)
+			return err
+		}
+		results, excluded := filterTargets(repoFilter, fetched)
+		printExclusions(excluded)
+		repositories, labels := mergeRepositories(results)
+
+		if err := checkCollisions(repositories, gitOptions.Layout); err != nil {
+			return err
 		}
 
 		// Convert to SSH URLs if --ssh flag is set
 		if useSSH {
-			for i := range repositories {
-				if repositories[i].SSHURL != "" {
-					repositories[i].CloneURL = repositories[i].SSHURL
-					if verbose {
-						fmt.Printf("Using SSH URL for %s: %s\n", repositories[i].FullName, repositories[i].SSHURL)
-					}
-				} else if verbose {
-					fmt.Printf("Warning: No SSH URL available for %s, using HTTPS\n", repositories[i].FullName)
-				}
-			}
+			useSSHURLs(repositories)
+		}
+
+		// Move clones of renamed repositories before looking for existing clones
+		if err := relocateRenamed(repositories, gitOptions); err != nil {
+			return err
 		}
 
 		fmt.Printf("Found %d repositories to clone\n", len(repositories))
 
 		// Create worker pool and start cloning
-		wp := worker.NewWorkerPool(threads, verbose)
+		wp := worker.NewWorkerPoolWithOptions(threads, verbose, gitOptions)
 		resultChan := wp.CloneRepositories(ctx, repositories, directory)
 
 		// Process results
-		var successful, failed, skipped int
+		var total summary
+		var submoduleFailures int
+		var lfsFailures int
+		var present []types.Repository
+		breakdown := make(map[string]*summary)
 		for result := range resultChan {
+			label := labels[repositoryKey(result.Repository)]
+			if breakdown[label] == nil {
+				breakdown[label] = &summary{}
+			}
+			counts := []*summary{&total, breakdown[label]}
+
 			if result.Error != nil {
 				if verbose {
 					fmt.Printf("❌ %s: %v\n", result.Repository.FullName, result.Error)
 				}
-				if result.Error.Error() == fmt.Sprintf("repository already exists at %s",
-					filepath.Join(directory, result.Repository.Owner, result.Repository.Name)) {
-					skipped++
-				} else {
-					failed++
+				for _, c := range counts {
+					c.Failed++
+				}
+			} else if result.Skipped {
+				present = append(present, result.Repository)
+				if verbose {
+					fmt.Printf("⏭️  %s: already exists\n", result.Repository.FullName)
+				}
+				for _, c := range counts {
+					c.Skipped++
 				}
 			} else if result.Success {
+				present = append(present, result.Repository)
+				if printSubmoduleError(result.Repository, result.SubmoduleError) {
+					submoduleFailures++
+				}
+				if printLFSError(result.Repository, result.LFSError) {
+					lfsFailures++
+				}
 				if verbose {
 					fmt.Printf("✅ %s (%.2fs)\n", result.Repository.FullName, result.Duration.Seconds())
 				}
-				successful++
+				for _, c := range counts {
+					c.Successful++
+				}
 			}
 		}
 
+		// Keep the metadata of the repositories for the commands working on the baseline alone
+		located := inBaseline(present, gitOptions.Layout)
+		if err := catalog.Record(directory, located); err != nil {
+			fmt.Printf("⚠️  Failed to record repository metadata: %v\n", err)
+		}
+
+		indexEnabled := keepIndexes()
+		var indexed, indexFailures int
+		if indexEnabled {
+			indexed, _, indexFailures = refreshIndexes(ctx, wp, located)
+		}
+		symbolsEnabled := keepSymbols()
+		var symbolsRefreshed, symbolsFailures int
+		if symbolsEnabled {
+			symbolsRefreshed, symbolsFailures = refreshSymbols(ctx, wp, located)
+		}
+
 		// Print summary
 		fmt.Printf("\nClone Summary:\n")
-		fmt.Printf("  Successful: %d\n", successful)
-		fmt.Printf("  Skipped:    %d (already exists)\n", skipped)
-		fmt.Printf("  Failed:     %d\n", failed)
+		fmt.Printf("  Successful: %d\n", total.Successful)
+		fmt.Printf("  Skipped:    %d (already exists)\n", total.Skipped)
+		fmt.Printf("  Failed:     %d\n", total.Failed)
+		if submoduleFailures > 0 {
+			fmt.Printf("  Submodules: %d failed\n", submoduleFailures)
+		}
+		if lfsFailures > 0 {
+			fmt.Printf("  LFS:        %d failed\n", lfsFailures)
+		}
+		if indexEnabled {
+			fmt.Printf("  Indexed:    %d (%d failed)\n", indexed, indexFailures)
+		}
+		if symbolsEnabled {
+			fmt.Printf("  Symbols:    %d (%d failed)\n", symbolsRefreshed, symbolsFailures)
+		}
+		printBreakdown(targets, breakdown, false)
 
-		if failed > 0 {
+		if total.Failed > 0 {
 			return fmt.Errorf("some repositories failed to clone")
 		}
 
@@ -123,5 +205,15 @@ when you have SSH keys configured and want to avoid HTTPS authentication issues.
 
 func init() {
 	rootCmd.AddCommand(cloneCmd)
+	cloneCmd.Flags().IntVar(&cloneDepth, "depth", 0, "Clone only the given number of commits of history (0 clones the full history)")
+	cloneCmd.Flags().StringVar(&cloneShallowSince, "shallow-since", "", "Clone only the history after the given date, e.g. 2024-01-01")
+	cloneCmd.Flags().StringVar(&cloneFilter, "filter", "", "Partial clone filter, e.g. blob:none (blobless) or tree:0 (treeless)")
+	cloneCmd.Flags().StringArrayVar(&cloneStrategies, "strategy", nil, "Clone strategy for matching repositories as pattern:options, e.g. 'acme/monorepo:depth=1,filter=blob:none', may be repeated")
+	addSparseFlags(cloneCmd)
+	addSubmoduleFlag(cloneCmd)
+	addIndexFlag(cloneCmd)
+	addSymbolsFlag(cloneCmd)
+	addLFSFlag(cloneCmd)
+	cloneCmd.Flags().BoolVar(&convertMode, "convert-mode", false, "Replace repositories stored in the other mode with a fresh clone in the selected --mode")
 	cloneCmd.Flags().BoolVar(&useSSH, "ssh", false, "Use SSH URLs for cloning instead of HTTPS")
 }
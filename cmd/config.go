@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jonasbn/baseline/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// nonSettings lists flags that cannot be set from a profile
+var nonSettings = map[string]bool{
+	"config":  true,
+	"profile": true,
+	"help":    true,
+}
+
+// applyConfiguration loads the user configuration file and the per-baseline
+// .baseline.yaml and sets every flag not given on the command line (or through
+// the environment) from the selected profile.
+// Precedence: flags, environment, per-baseline file, user file, defaults.
+func applyConfiguration(cmd *cobra.Command) error {
+	flags := cmd.Flags()
+
+	userFile, err := loadUserConfig(flags)
+	if err != nil {
+		return err
+	}
+
+	// The user profile may choose the baseline directory holding the per-baseline file
+	name := profileName
+	if name == "" && userFile != nil {
+		name = userFile.DefaultProfile
+	}
+	userProfile, _ := userFile.Profile(name)
+
+	baselineDir := directory
+	if setting, ok := userProfile["directory"]; ok && !flags.Changed("directory") {
+		values, err := setting.Expand()
+		if err != nil {
+			return err
+		}
+		baselineDir = values[len(values)-1]
+	}
+
+	baselineFile, err := config.Load(filepath.Join(baselineDir, config.BaselineFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if name == "" && baselineFile != nil {
+		name = baselineFile.DefaultProfile
+		userProfile, _ = userFile.Profile(name)
+	}
+
+	if name == "" {
+		return nil
+	}
+
+	baselineProfile, inBaseline := baselineFile.Profile(name)
+	if _, inUser := userFile.Profile(name); !inUser && !inBaseline {
+		return fmt.Errorf("profile %q not found", name)
+	}
+
+	profile := userProfile.Merge(baselineProfile)
+	if err := validateProfile(cmd.Root(), name, profile); err != nil {
+		return err
+	}
+
+	if verbose {
+		fmt.Printf("Using profile: %s\n", name)
+	}
+
+	return applyProfile(flags, profile)
+}
+
+// loadUserConfig loads the file given with --config or the default user configuration file
+func loadUserConfig(flags *pflag.FlagSet) (*config.File, error) {
+	path := configFile
+	if path == "" {
+		defaultPath, err := config.DefaultPath()
+		if err != nil {
+			return nil, nil
+		}
+		path = defaultPath
+	}
+
+	file, err := config.Load(path)
+	if err != nil {
+		// Only a configuration file given explicitly has to exist
+		if os.IsNotExist(err) && !flags.Changed("config") && os.Getenv("BASELINE_CONFIG") == "" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	return file, nil
+}
+
+// validateProfile reports settings that do not correspond to a flag of any command
+func validateProfile(root *cobra.Command, name string, profile config.Profile) error {
+	known := make(map[string]bool)
+	var collect func(c *cobra.Command)
+	collect = func(c *cobra.Command) {
+		c.Flags().VisitAll(func(f *pflag.Flag) { known[f.Name] = true })
+		c.PersistentFlags().VisitAll(func(f *pflag.Flag) { known[f.Name] = true })
+		for _, sub := range c.Commands() {
+			collect(sub)
+		}
+	}
+	collect(root)
+
+	keys := make([]string, 0, len(profile))
+	for key := range profile {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !known[key] || nonSettings[key] {
+			setting := profile[key]
+			return fmt.Errorf("%s:%d: unknown setting %q in profile %s", setting.File, setting.Line, key, name)
+		}
+	}
+
+	return nil
+}
+
+// applyProfile sets the flags of the command that were not changed from the profile.
+// Settings for flags of other commands are ignored.
+func applyProfile(flags *pflag.FlagSet, profile config.Profile) error {
+	for key, setting := range profile {
+		if flags.Lookup(key) == nil || flags.Changed(key) {
+			continue
+		}
+
+		values, err := setting.Expand()
+		if err != nil {
+			return err
+		}
+
+		for _, value := range values {
+			if err := flags.Set(key, value); err != nil {
+				return fmt.Errorf("%s:%d: invalid value for %s: %w", setting.File, setting.Line, key, err)
+			}
+		}
+	}
+
+	return nil
+}
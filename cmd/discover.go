@@ -3,10 +3,8 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"strings"
 
-	"github.com/jonasbn/baseline/internal/sources/bitbucket"
-	"github.com/jonasbn/baseline/internal/sources/github"
-	"github.com/jonasbn/baseline/internal/types"
 	"github.com/spf13/cobra"
 )
 
@@ -14,49 +12,76 @@ import (
 var discoverCmd = &cobra.Command{
 	Use:   "discover",
 	Short: "List repositories available in the specified source",
-	Long: `Discover and list all repositories available in the specified source (GitHub or Bitbucket)
+	Long: `Discover and list all repositories available in the specified source (GitHub, Bitbucket, GitLab, Gitea or Azure DevOps)
 for the given organization.
 
-This command helps you see what repositories are available before cloning them.`,
+This command helps you see what repositories are available before cloning them.
+Use the --target flag, repeatedly, to discover several sources and organizations at once.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
 
-		// Create the appropriate source client
-		var sourceClient types.RepositorySource
-		var err error
-
-		switch source {
-		case "github":
-			sourceClient = github.NewGitHubClient(githubToken)
-		case "bitbucket":
-			sourceClient = bitbucket.NewBitbucketClient(bitbucketUser, bitbucketToken, verbose)
-		default:
-			return fmt.Errorf("unsupported source: %s (supported: github, bitbucket)", source)
+		targets, err := resolveTargets()
+		if err != nil {
+			return err
 		}
 
 		if verbose {
-			fmt.Printf("Discovering repositories from %s for organization/user: %s\n", source, organization)
+			for _, t := range targets {
+				fmt.Printf("Discovering repositories from %s for organization/user: %s\n", t.Source, t.Organization)
+			}
+		}
+
+		repoFilter, err := newFilter()
+		if err != nil {
+			return err
 		}
 
-		// Fetch repositories
-		repositories, err := sourceClient.GetRepositories(ctx, organization)
+		// Fetch repositories from all targets
+		fetched, err := fetchTargets(ctx, targets)
 		if err != nil {
-			return fmt.Errorf("failed to fetch repositories: %w", err)
+			return err
+		}
+		results, excluded := filterTargets(repoFilter, fetched)
+
+		l, err := newLayout()
+		if err != nil {
+			return err
 		}
 
 		// Display results
-		fmt.Printf("Found %d repositories in %s/%s:\n", len(repositories), source, organization)
-		fmt.Println()
+		for i, result := range results {
+			if i > 0 {
+				fmt.Println()
+			}
+			if exclusions := excluded[result.target.String()]; len(exclusions) > 0 {
+				fmt.Printf("Found %d repositories in %s (%d excluded by filters):\n", len(result.repositories), result.target, len(exclusions))
+			} else {
+				fmt.Printf("Found %d repositories in %s:\n", len(result.repositories), result.target)
+			}
+			fmt.Println()
+
+			for _, repo := range result.repositories {
+				fmt.Printf("  %-30s %s\n", repo.Name, repo.Description)
+				if verbose {
+					fmt.Printf("    Full name: %s\n", repo.FullName)
+					fmt.Printf("    Clone URL: %s\n", repo.CloneURL)
+					fmt.Printf("    Path:      %s\n", l.Path(repo, directory))
+					fmt.Printf("    Language:  %s\n", repo.Language)
+					fmt.Printf("    Private:   %t\n", repo.Private)
+					fmt.Printf("    Archived:  %t\n", repo.Archived)
+					fmt.Printf("    Fork:      %t\n", repo.Fork)
+					if len(repo.Topics) > 0 {
+						fmt.Printf("    Topics:    %s\n", strings.Join(repo.Topics, ", "))
+					}
+					fmt.Printf("    Updated:   %s\n", repo.UpdatedAt.Format("2006-01-02 15:04:05"))
+					fmt.Println()
+				}
+			}
 
-		for _, repo := range repositories {
-			fmt.Printf("  %-30s %s\n", repo.Name, repo.Description)
 			if verbose {
-				fmt.Printf("    Full name: %s\n", repo.FullName)
-				fmt.Printf("    Clone URL: %s\n", repo.CloneURL)
-				fmt.Printf("    Language:  %s\n", repo.Language)
-				fmt.Printf("    Private:   %t\n", repo.Private)
-				fmt.Printf("    Updated:   %s\n", repo.UpdatedAt.Format("2006-01-02 15:04:05"))
-				fmt.Println()
+				for _, e := range excluded[result.target.String()] {
+					fmt.Printf("  %-30s excluded: %s\n", e.Repository.Name, e.Reason)
+				}
 			}
 		}
 
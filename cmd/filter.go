@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/jonasbn/baseline/internal/filter"
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// newFilter creates the repository filter selected with the filter flags
+func newFilter() (*filter.Filter, error) {
+	return filter.New(filter.Options{
+		ExcludeArchived: excludeArchived,
+		ExcludeForks:    excludeForks,
+		ExcludeDisabled: excludeDisabled,
+		SkipEmpty:       skipEmpty,
+		Include:         includePatterns,
+		Exclude:         excludePatterns,
+		Languages:       languages,
+		Topics:          topics,
+		Visibility:      visibility,
+		UpdatedSince:    updatedSince,
+		UpdatedBefore:   updatedBefore,
+	})
+}
+
+// filterTargets applies the filter to the repositories of each target and returns
+// the repositories to keep and the excluded ones per target label
+func filterTargets(f *filter.Filter, results []targetRepositories) ([]targetRepositories, map[string][]filter.Exclusion) {
+	kept := make([]targetRepositories, len(results))
+	excluded := make(map[string][]filter.Exclusion)
+
+	for i, result := range results {
+		if len(languages) > 0 && len(result.repositories) > 0 && !reportsLanguages(result.repositories) {
+			fmt.Printf("⚠️  %s: no languages reported, --language keeps all %d repositories\n", result.target, len(result.repositories))
+		}
+
+		repositories, exclusions := f.Apply(result.repositories)
+		kept[i] = targetRepositories{target: result.target, repositories: repositories}
+		if len(exclusions) > 0 {
+			excluded[result.target.String()] = exclusions
+		}
+	}
+
+	return kept, excluded
+}
+
+// reportsLanguages reports whether any of the repositories has a known language,
+// sources without languages leave them all unknown and --language keeps them
+func reportsLanguages(repositories []types.Repository) bool {
+	for _, repo := range repositories {
+		if repo.Language != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// printExclusions prints the number of excluded repositories, and each of them with
+// --verbose, ordered by target and full name
+func printExclusions(excluded map[string][]filter.Exclusion) {
+	labels := make([]string, 0, len(excluded))
+	var count int
+	for label, exclusions := range excluded {
+		labels = append(labels, label)
+		count += len(exclusions)
+	}
+
+	if verbose {
+		sort.Strings(labels)
+		for _, label := range labels {
+			exclusions := slices.Clone(excluded[label])
+			sort.SliceStable(exclusions, func(i, j int) bool {
+				return exclusions[i].Repository.FullName < exclusions[j].Repository.FullName
+			})
+			for _, e := range exclusions {
+				fmt.Printf("🚫 %s: excluded (%s)\n", e.Repository.FullName, e.Reason)
+			}
+		}
+	}
+
+	if count > 0 {
+		fmt.Printf("Excluded %d repositories by filters\n", count)
+	}
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jonasbn/baseline/internal/catalog"
+	"github.com/jonasbn/baseline/internal/index"
+	"github.com/jonasbn/baseline/internal/layout"
+	"github.com/jonasbn/baseline/internal/types"
+	"github.com/jonasbn/baseline/internal/worker"
+	"github.com/spf13/cobra"
+)
+
+var buildIndex bool
+
+// indexCmd represents the index command
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Build or refresh the search index of all repositories in the baseline",
+	Long: `Build the trigram index of every repository in the baseline directory, or bring an
+existing index up to date with the files changed since it was built. The indexes are
+stored in .baseline/index below the baseline directory and let search --index read only
+the files that may match.
+
+clone --index and update --index refresh the indexes of the repositories they touch.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		entries, err := catalog.Scan(directory)
+		if err != nil {
+			return err
+		}
+		repositories := baselineRepositories(entries)
+		if len(repositories) == 0 {
+			return fmt.Errorf("no repositories to index in %s", directory)
+		}
+
+		fmt.Printf("Found %d repositories to index\n", len(repositories))
+
+		wp := worker.NewWorkerPool(threads, verbose)
+		indexed, rebuilt, failed := refreshIndexes(ctx, wp, repositories)
+
+		// Print summary
+		fmt.Printf("\nIndex Summary:\n")
+		fmt.Printf("  Rebuilt:    %d\n", rebuilt)
+		fmt.Printf("  Updated:    %d\n", indexed-rebuilt)
+		fmt.Printf("  Unchanged:  %d\n", len(repositories)-indexed-failed)
+		fmt.Printf("  Failed:     %d\n", failed)
+
+		if failed > 0 {
+			return fmt.Errorf("some repositories failed to index")
+		}
+
+		return nil
+	},
+}
+
+// refreshIndexes refreshes the search indexes of the repositories, stored at their BaselinePath,
+// and returns the number of indexes changed, rebuilt from scratch and failed
+func refreshIndexes(ctx context.Context, wp *worker.WorkerPool, repositories []types.Repository) (int, int, int) {
+	var indexed, rebuilt, failed int
+	for result := range wp.IndexRepositories(ctx, repositories, directory) {
+		switch {
+		case result.Error != nil:
+			// Reported also without --verbose, the repository is searched without index
+			fmt.Printf("⚠️  %s: index: %v\n", result.Repository.FullName, result.Error)
+			failed++
+		case result.Rebuilt:
+			if verbose {
+				fmt.Printf("🔎 %s: indexed %d files (%.2fs)\n", result.Repository.FullName, result.Changed, result.Duration.Seconds())
+			}
+			indexed++
+			rebuilt++
+		case result.Changed > 0:
+			if verbose {
+				fmt.Printf("🔎 %s: reindexed %d changed files (%.2fs)\n", result.Repository.FullName, result.Changed, result.Duration.Seconds())
+			}
+			indexed++
+		}
+	}
+	return indexed, rebuilt, failed
+}
+
+// keepIndexes reports whether clone and update refresh the search indexes of the
+// repositories they touch: when asked to, or when the baseline has indexes
+func keepIndexes() bool {
+	return buildIndex || index.Store.Exists(directory)
+}
+
+// inBaseline returns copies of the repositories with BaselinePath set to their path in the baseline
+func inBaseline(repositories []types.Repository, l *layout.Layout) []types.Repository {
+	located := make([]types.Repository, len(repositories))
+	for i, repo := range repositories {
+		repo.BaselinePath = l.Path(repo, directory)
+		located[i] = repo
+	}
+	return located
+}
+
+// addIndexFlag adds the index flag shared by clone and update
+func addIndexFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&buildIndex, "index", false, "Build or refresh the search index of the repositories, see the index command")
+}
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+}
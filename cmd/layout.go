@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jonasbn/baseline/internal/catalog"
+	"github.com/jonasbn/baseline/internal/git"
+	"github.com/jonasbn/baseline/internal/index"
+	"github.com/jonasbn/baseline/internal/layout"
+	"github.com/jonasbn/baseline/internal/symbols"
+	"github.com/jonasbn/baseline/internal/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sparsePaths     []string
+	sparseNoCone    bool
+	sparseRuleSpecs []string
+	submodules      bool
+	lfsPolicy       string
+)
+
+// newLayout resolves the layout selected with --layout or --layout-template
+func newLayout() (*layout.Layout, error) {
+	if layoutTemplate != "" {
+		return layout.New(layoutTemplate)
+	}
+	return layout.Resolve(layoutName)
+}
+
+// newGitOptions returns the Git options shared by all commands
+func newGitOptions() (git.Options, error) {
+	l, err := newLayout()
+	if err != nil {
+		return git.Options{}, err
+	}
+
+	mode, err := git.ParseMode(storageMode)
+	if err != nil {
+		return git.Options{}, err
+	}
+
+	strategy := git.Strategy{Depth: cloneDepth, ShallowSince: cloneShallowSince, Filter: cloneFilter}
+	if err := strategy.Validate(); err != nil {
+		return git.Options{}, fmt.Errorf("invalid clone strategy: %w", err)
+	}
+
+	rules := make([]git.StrategyRule, 0, len(cloneStrategies))
+	for _, spec := range cloneStrategies {
+		rule, err := git.ParseStrategyRule(spec)
+		if err != nil {
+			return git.Options{}, err
+		}
+		rules = append(rules, rule)
+	}
+
+	sparse := git.Sparse{Patterns: sparsePaths, NoCone: sparseNoCone}
+	sparseRules := make([]git.SparseRule, 0, len(sparseRuleSpecs))
+	for _, spec := range sparseRuleSpecs {
+		rule, err := git.ParseSparseRule(spec)
+		if err != nil {
+			return git.Options{}, err
+		}
+		sparseRules = append(sparseRules, rule)
+	}
+
+	lfs, err := git.ParseLFSPolicy(lfsPolicy)
+	if err != nil {
+		return git.Options{}, err
+	}
+	// Look for git-lfs on every run, repositories using LFS are reported when it is missing
+	lfsVersion, lfsErr := git.LFSVersion()
+	if lfsErr != nil && lfs.RequiresLFS() {
+		return git.Options{}, fmt.Errorf("LFS policy %s requires git-lfs: %w", lfs, lfsErr)
+	}
+	if verbose {
+		switch {
+		case lfsErr != nil:
+			fmt.Printf("git-lfs is not installed, LFS content is not downloaded\n")
+		case lfs.Mode != git.LFSDefault:
+			fmt.Printf("Using %s with LFS policy %s\n", lfsVersion, lfs)
+		default:
+			fmt.Printf("Using %s\n", lfsVersion)
+		}
+	}
+
+	return git.Options{
+		Layout:        l,
+		Mode:          mode,
+		ConvertMode:   convertMode,
+		Strategy:      strategy,
+		StrategyRules: rules,
+		Sparse:        sparse,
+		SparseRules:   sparseRules,
+		Submodules:    submodules,
+		LFS:           lfs,
+		LFSMissing:    lfsErr != nil,
+	}, nil
+}
+
+// addSparseFlags adds the sparse checkout flags shared by clone and update
+func addSparseFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&sparsePaths, "sparse", nil, "Check out only the given directory, may be repeated")
+	cmd.Flags().BoolVar(&sparseNoCone, "sparse-no-cone", false, "Treat --sparse values as gitignore-style patterns instead of directories")
+	cmd.Flags().StringArrayVar(&sparseRuleSpecs, "sparse-rule", nil, "Sparse checkout for matching repositories as pattern:paths, e.g. 'acme/monorepo:src,docs', may be repeated")
+}
+
+// addSubmoduleFlag adds the submodule flag shared by clone and update
+func addSubmoduleFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&submodules, "submodules", false, "Initialize and update submodules recursively")
+}
+
+// addLFSFlag adds the Git LFS flag shared by clone and update
+func addLFSFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&lfsPolicy, "lfs", "", "Git LFS policy: skip, pointers, fetch or fetch-include=<glob> (default: the Git configuration)")
+}
+
+// printLFSError reports a repository whose LFS content failed to download, also without --verbose
+func printLFSError(repo types.Repository, err error) bool {
+	if err == nil {
+		return false
+	}
+	fmt.Printf("⚠️  %s: LFS: %v\n", repo.FullName, err)
+	return true
+}
+
+// printSubmoduleError reports a repository whose submodules failed, also without --verbose
+func printSubmoduleError(repo types.Repository, err error) bool {
+	if err == nil {
+		return false
+	}
+	fmt.Printf("⚠️  %s: submodules: %v\n", repo.FullName, err)
+	return true
+}
+
+// relocateRenamed moves existing clones of repositories renamed or transferred upstream
+// to the path of their new name, so they are updated instead of cloned again
+func relocateRenamed(repositories []types.Repository, opts git.Options) error {
+	gitOps := git.NewGitOpsWithOptions(verbose, opts)
+	relocations, err := gitOps.RelocateRenamed(repositories, directory)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range relocations {
+		oldRel, _ := filepath.Rel(directory, r.OldPath)
+		newRel, _ := filepath.Rel(directory, r.NewPath)
+		if r.Duplicate {
+			fmt.Printf("⚠️  %s: another clone of %s, kept at %s, not moved\n", oldRel, r.Repository.FullName, newRel)
+			continue
+		}
+		if r.Error != nil {
+			fmt.Printf("❌ %s: renamed upstream to %s: %v\n", oldRel, r.Repository.FullName, r.Error)
+			continue
+		}
+		fmt.Printf("🚚 %s -> %s (renamed or transferred upstream)\n", oldRel, newRel)
+		if err := index.Store.Move(directory, r.OldPath, r.NewPath); err != nil {
+			fmt.Printf("⚠️  %s: failed to move index: %v\n", newRel, err)
+		}
+		if err := symbols.Store.Move(directory, r.OldPath, r.NewPath); err != nil {
+			fmt.Printf("⚠️  %s: failed to move symbols: %v\n", newRel, err)
+		}
+		if err := catalog.Move(directory, r.OldPath, r.NewPath); err != nil {
+			fmt.Printf("⚠️  %s: failed to move metadata: %v\n", newRel, err)
+		}
+	}
+
+	return nil
+}
+
+// checkCollisions reports repositories of the run that the layout maps to the same directory
+func checkCollisions(repositories []types.Repository, l *layout.Layout) error {
+	paths := make(map[string][]string)
+	for _, repo := range repositories {
+		rel := l.RelativePath(repo)
+		paths[rel] = append(paths[rel], repo.Source+":"+repo.FullName)
+	}
+
+	var collisions []string
+	for rel, repos := range paths {
+		if len(repos) > 1 {
+			collisions = append(collisions, fmt.Sprintf("  %s: %s", rel, strings.Join(repos, ", ")))
+		}
+	}
+
+	if len(collisions) == 0 {
+		return nil
+	}
+
+	sort.Strings(collisions)
+	return fmt.Errorf("repositories map to the same directory with layout %s, use --layout host or a --layout-template:\n%s",
+		l, strings.Join(collisions, "\n"))
+}
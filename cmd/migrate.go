@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/jonasbn/baseline/internal/catalog"
+	"github.com/jonasbn/baseline/internal/git"
+	"github.com/jonasbn/baseline/internal/index"
+	"github.com/jonasbn/baseline/internal/layout"
+	"github.com/jonasbn/baseline/internal/symbols"
+	"github.com/jonasbn/baseline/internal/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateFrom   string
+	migrateDryRun bool
+)
+
+// migrateLayoutCmd represents the migrate-layout command
+var migrateLayoutCmd = &cobra.Command{
+	Use:   "migrate-layout",
+	Short: "Move an existing baseline to a new directory layout",
+	Long: `Move the repositories of an existing baseline from one directory layout to another.
+
+The current layout is given with --from (owner, host or a template), the new layout is
+the one selected with --layout or --layout-template. The host of each repository is
+read from its origin remote, so no source needs to be contacted.
+
+Example: baseline migrate-layout --from owner --layout host`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, err := layout.Resolve(migrateFrom)
+		if err != nil {
+			return fmt.Errorf("invalid --from layout: %w", err)
+		}
+
+		to, err := newLayout()
+		if err != nil {
+			return err
+		}
+
+		if from.String() == to.String() {
+			return fmt.Errorf("the baseline already uses layout %s", to)
+		}
+
+		paths, err := git.FindRepositories(directory)
+		if err != nil {
+			return fmt.Errorf("failed to scan %s: %w", directory, err)
+		}
+
+		gitOps := git.NewGitOpsWithOptions(verbose, git.Options{Layout: to})
+
+		var moved, skipped, failed int
+		for _, oldPath := range paths {
+			rel, err := filepath.Rel(directory, oldPath)
+			if err != nil {
+				return err
+			}
+
+			originURL, _ := git.RemoteURL(oldPath, "origin")
+
+			// Repositories already stored according to the new layout are left alone,
+			// which makes it safe to run the migration again after an interruption
+			if fields, ok := to.Parse(rel); ok && gitOps.RepositoryPath(repositoryFromFields(fields, originURL), directory) == oldPath {
+				skipped++
+				continue
+			}
+
+			fields, ok := from.Parse(rel)
+			if !ok {
+				fmt.Printf("⏭️  %s: does not match layout %s\n", rel, from)
+				skipped++
+				continue
+			}
+
+			newPath := gitOps.RepositoryPath(repositoryFromFields(fields, originURL), directory)
+			if newPath == oldPath {
+				skipped++
+				continue
+			}
+
+			newRel, _ := filepath.Rel(directory, newPath)
+			if migrateDryRun {
+				fmt.Printf("Would move %s to %s\n", rel, newRel)
+				moved++
+				continue
+			}
+
+			if err := gitOps.MoveRepository(oldPath, newPath, directory); err != nil {
+				fmt.Printf("❌ %s: %v\n", rel, err)
+				failed++
+				continue
+			}
+
+			fmt.Printf("🚚 %s -> %s\n", rel, newRel)
+			if err := index.Store.Move(directory, oldPath, newPath); err != nil {
+				fmt.Printf("⚠️  %s: failed to move index: %v\n", newRel, err)
+			}
+			if err := symbols.Store.Move(directory, oldPath, newPath); err != nil {
+				fmt.Printf("⚠️  %s: failed to move symbols: %v\n", newRel, err)
+			}
+			if err := catalog.Move(directory, oldPath, newPath); err != nil {
+				fmt.Printf("⚠️  %s: failed to move metadata: %v\n", newRel, err)
+			}
+			moved++
+		}
+
+		// Print summary
+		fmt.Printf("\nMigrate Summary:\n")
+		if migrateDryRun {
+			fmt.Printf("  Would move: %d (dry run)\n", moved)
+		} else {
+			fmt.Printf("  Moved:      %d\n", moved)
+		}
+		fmt.Printf("  Skipped:    %d\n", skipped)
+		fmt.Printf("  Failed:     %d\n", failed)
+
+		if failed > 0 {
+			return fmt.Errorf("some repositories failed to move")
+		}
+
+		return nil
+	},
+}
+
+// repositoryFromFields describes a local repository by its layout fields and origin remote
+func repositoryFromFields(fields layout.Fields, originURL string) types.Repository {
+	return types.Repository{
+		Name:     fields.Name,
+		FullName: fields.Owner + "/" + fields.Name,
+		Owner:    fields.Owner,
+		Source:   fields.Source,
+		CloneURL: originURL,
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(migrateLayoutCmd)
+	migrateLayoutCmd.Flags().StringVar(&migrateFrom, "from", "owner", "Current layout of the baseline (owner, host or a template)")
+	migrateLayoutCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Show what would be moved without moving anything")
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/jonasbn/baseline/internal/git"
+	"github.com/jonasbn/baseline/internal/index"
+	"github.com/jonasbn/baseline/internal/layout"
+	"github.com/jonasbn/baseline/internal/prune"
+	"github.com/jonasbn/baseline/internal/symbols"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pruneAction string
+	pruneDryRun bool
+)
+
+// pruneCmd represents the prune command
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Find repositories in the baseline that no longer exist in the specified source",
+	Long: `Compare the repositories in the baseline directory with the repositories returned by the
+specified sources and list the orphans: repositories that were deleted, renamed or moved
+upstream.
+
+Only the part of the baseline belonging to the sources and organizations of the run is
+considered. With --action archive the orphans are moved to _archive/<timestamp>/ below the
+baseline directory, with --action delete they are removed. The command defaults to a dry
+run, pass --dry-run=false to archive or delete.
+
+Example: baseline prune -o myorg --action archive --dry-run=false`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		action, err := prune.ParseAction(pruneAction)
+		if err != nil {
+			return err
+		}
+
+		l, err := newLayout()
+		if err != nil {
+			return err
+		}
+
+		targets, err := resolveTargets()
+		if err != nil {
+			return err
+		}
+
+		// Fetch repositories from all targets
+		results, err := fetchTargets(ctx, targets)
+		if err != nil {
+			return err
+		}
+
+		return runPrune(results, l, action, pruneDryRun)
+	},
+}
+
+// runPrune lists the orphaned repositories of the targets and archives or deletes them
+func runPrune(results []targetRepositories, l *layout.Layout, action prune.Action, dryRun bool) error {
+	var scope prune.Scope
+	for _, result := range results {
+		// An empty list is more likely a permission problem than an empty organization
+		if len(result.repositories) == 0 {
+			return fmt.Errorf("%s returned no repositories, refusing to prune", result.target)
+		}
+		scope.Sources = append(scope.Sources, result.target.Source)
+		scope.Owners = append(scope.Owners, result.target.Organization)
+	}
+	repositories, _ := mergeRepositories(results)
+
+	orphans, err := prune.Find(directory, l, repositories, scope)
+	if err != nil {
+		return err
+	}
+
+	gitOps := git.NewGitOpsWithOptions(verbose, git.Options{Layout: l})
+
+	// Clones of repositories renamed upstream are moved by clone and update, not pruned.
+	// Further clones with the same ID are never moved and are pruned as orphans.
+	renamed, err := gitOps.FindRenamed(repositories, directory)
+	if err != nil {
+		return err
+	}
+	renamedTo := make(map[string]string, len(renamed))
+	for _, r := range renamed {
+		if !r.Duplicate {
+			renamedTo[r.OldPath] = r.Repository.FullName
+		}
+	}
+	archiveDir := filepath.Join(directory, git.ArchiveDir, time.Now().UTC().Format("20060102-150405"))
+
+	var failed, skipped int
+	for _, orphan := range orphans {
+		if fullName, ok := renamedTo[orphan.Path]; ok {
+			fmt.Printf("🚚 %s: renamed upstream to %s, run update to move it\n", orphan.RelativePath, fullName)
+			skipped++
+			continue
+		}
+
+		if action == prune.ActionReport || dryRun {
+			switch action {
+			case prune.ActionArchive:
+				fmt.Printf("Would archive %s\n", orphan.RelativePath)
+			case prune.ActionDelete:
+				fmt.Printf("Would delete %s\n", orphan.RelativePath)
+			default:
+				fmt.Printf("🗑️  %s: no longer exists in the source\n", orphan.RelativePath)
+			}
+			continue
+		}
+
+		switch action {
+		case prune.ActionArchive:
+			err = gitOps.MoveRepository(orphan.Path, filepath.Join(archiveDir, orphan.RelativePath), directory)
+		case prune.ActionDelete:
+			err = gitOps.DeleteRepository(orphan.Path, directory)
+		}
+
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", orphan.RelativePath, err)
+			failed++
+			continue
+		}
+
+		// The index and symbols of a repository no longer in the baseline are of no use
+		if err := index.Store.Remove(directory, orphan.Path); err != nil && verbose {
+			fmt.Printf("⚠️  %s: failed to remove index: %v\n", orphan.RelativePath, err)
+		}
+		if err := symbols.Store.Remove(directory, orphan.Path); err != nil && verbose {
+			fmt.Printf("⚠️  %s: failed to remove symbols: %v\n", orphan.RelativePath, err)
+		}
+
+		if action == prune.ActionArchive {
+			fmt.Printf("📦 %s: archived\n", orphan.RelativePath)
+		} else {
+			fmt.Printf("🗑️  %s: deleted\n", orphan.RelativePath)
+		}
+	}
+
+	// Print summary
+	fmt.Printf("\nPrune Summary:\n")
+	fmt.Printf("  Orphans:    %d\n", len(orphans)-skipped)
+	if skipped > 0 {
+		fmt.Printf("  Renamed:    %d\n", skipped)
+	}
+	if action != prune.ActionReport && dryRun && len(orphans) > skipped {
+		fmt.Printf("  Dry run, pass --dry-run=false to %s them\n", action)
+	}
+	if failed > 0 {
+		fmt.Printf("  Failed:     %d\n", failed)
+		return fmt.Errorf("some repositories failed to %s", action)
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+	pruneCmd.Flags().StringVar(&pruneAction, "action", string(prune.ActionReport), "What to do with orphaned repositories: report, archive or delete")
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", true, "Show what would be archived or deleted without changing anything")
+}
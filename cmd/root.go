@@ -4,19 +4,54 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/jonasbn/baseline/internal/sources/azuredevops"
+	"github.com/jonasbn/baseline/internal/sources/github"
+	"github.com/jonasbn/baseline/internal/sources/gitlab"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
 	// Global flags
-	directory      string
-	githubToken    string
-	bitbucketUser  string
-	bitbucketToken string
-	organization   string
-	verbose        bool
-	source         string
-	threads        int
+	configFile           string
+	profileName          string
+	directory            string
+	githubToken          string
+	githubAPIURL         string
+	githubCACert         string
+	bitbucketUser        string
+	bitbucketToken       string
+	bitbucketServerToken string
+	bitbucketServerURL   string
+	gitlabToken          string
+	gitlabURL            string
+	giteaToken           string
+	giteaURL             string
+	azureDevOpsToken     string
+	azureDevOpsURL       string
+	manifestFile         string
+	localRoot            string
+	localOffline         bool
+	organization         string
+	verbose              bool
+	source               string
+	targetSpecs          []string
+	layoutName           string
+	layoutTemplate       string
+	storageMode          string
+	convertMode          bool
+	excludeArchived      bool
+	excludeForks         bool
+	excludeDisabled      bool
+	skipEmpty            bool
+	includePatterns      []string
+	excludePatterns      []string
+	languages            []string
+	topics               []string
+	visibility           string
+	updatedSince         string
+	updatedBefore        string
+	threads              int
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -25,9 +60,41 @@ var rootCmd = &cobra.Command{
 	Short: "A tool for creating a baseline of Git repositories for easy searching",
 	Long: `baseline is a Go program that creates a baseline of Git repositories for easy searching.
 
-It integrates with GitHub and Bitbucket to clone repositories into a specified directory,
-setting permissions to disallow write access, making them suitable for searching rather 
-than active development.`,
+It integrates with GitHub, Bitbucket (Cloud and Server), GitLab, Gitea/Forgejo and
+Azure DevOps to clone repositories into a specified directory, setting permissions
+to disallow write access, making them suitable for searching rather than active
+development.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyEnvironment(cmd.Flags()); err != nil {
+			return err
+		}
+		return applyConfiguration(cmd)
+	},
+}
+
+// envFlags maps flags to the environment variables that provide their value
+// when the flag is not given on the command line
+var envFlags = map[string]string{
+	"config":         "BASELINE_CONFIG",
+	"profile":        "BASELINE_PROFILE",
+	"github-api-url": "BASELINE_GITHUB_API_URL",
+	"github-ca-cert": "BASELINE_GITHUB_CA_CERT",
+}
+
+// applyEnvironment sets flags that were not given on the command line from the environment
+func applyEnvironment(flags *pflag.FlagSet) error {
+	for name, env := range envFlags {
+		value, ok := os.LookupEnv(env)
+		if !ok || flags.Lookup(name) == nil || flags.Changed(name) {
+			continue
+		}
+
+		if err := flags.Set(name, value); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", env, err)
+		}
+	}
+
+	return nil
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -41,14 +108,48 @@ func Execute() {
 
 func init() {
 	// Global flags available to all commands
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Configuration file (default $XDG_CONFIG_HOME/baseline/config.yaml or ~/.config/baseline/config.yaml)")
+	rootCmd.PersistentFlags().StringVarP(&profileName, "profile", "P", "", "Configuration profile to use (default is the default_profile of the configuration)")
 	rootCmd.PersistentFlags().StringVarP(&directory, "directory", "d", "./baseline", "Target directory for the baseline")
 	rootCmd.PersistentFlags().StringVarP(&githubToken, "github-token", "g", "", "GitHub token for accessing private repositories")
+	rootCmd.PersistentFlags().StringVar(&githubAPIURL, "github-api-url", github.DefaultAPIURL, "GitHub API base URL, e.g. https://github.example.com/api/v3 for GitHub Enterprise Server")
+	rootCmd.PersistentFlags().StringVar(&githubCACert, "github-ca-cert", "", "PEM CA bundle to trust for the GitHub API, e.g. for an internal GitHub Enterprise Server certificate")
 	rootCmd.PersistentFlags().StringVarP(&bitbucketUser, "bitbucket-username", "u", "", "Bitbucket username or email for API authentication")
 	rootCmd.PersistentFlags().StringVarP(&bitbucketToken, "bitbucket-token", "b", "", "Bitbucket API token (repository, project, or workspace access token)")
-	rootCmd.PersistentFlags().StringVarP(&organization, "organization", "o", "jonasbn", "Organization to fetch repositories from")
+	rootCmd.PersistentFlags().StringVar(&bitbucketServerToken, "bitbucket-server-token", "", "Bitbucket Server/Data Center HTTP access token")
+	rootCmd.PersistentFlags().StringVar(&bitbucketServerURL, "bitbucket-server-url", "", "Base URL of the Bitbucket Server/Data Center instance (required for the bitbucket-server source)")
+	rootCmd.PersistentFlags().StringVar(&gitlabToken, "gitlab-token", "", "GitLab personal, group or project access token")
+	rootCmd.PersistentFlags().StringVar(&gitlabURL, "gitlab-url", gitlab.DefaultBaseURL, "Base URL of the GitLab instance")
+	rootCmd.PersistentFlags().StringVar(&giteaToken, "gitea-token", "", "Gitea/Forgejo access token")
+	rootCmd.PersistentFlags().StringVar(&giteaURL, "gitea-url", "", "Base URL of the Gitea/Forgejo instance (required for the gitea source)")
+	rootCmd.PersistentFlags().StringVar(&azureDevOpsToken, "azure-devops-token", "", "Azure DevOps personal access token (PAT)")
+	rootCmd.PersistentFlags().StringVar(&azureDevOpsURL, "azure-devops-url", azuredevops.DefaultBaseURL, "Base URL of Azure DevOps Services or an Azure DevOps Server collection")
+	rootCmd.PersistentFlags().StringVar(&manifestFile, "manifest", "", "YAML or JSON file listing repositories (required for the manifest source)")
+	rootCmd.PersistentFlags().StringVar(&localRoot, "local-root", "", "Directory tree to scan for existing clones (required for the local source)")
+	rootCmd.PersistentFlags().BoolVar(&localOffline, "local-offline", false, "Clone from the existing local clones instead of their origin remote")
+	rootCmd.PersistentFlags().StringVarP(&organization, "organization", "o", "jonasbn", "Organization to fetch repositories from (GitLab: group path, Bitbucket Server: project key or ~user, Azure DevOps: org or org/project)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output for debugging")
-	rootCmd.PersistentFlags().StringVarP(&source, "source", "s", "github", "Source platform (github or bitbucket)")
+	rootCmd.PersistentFlags().StringArrayVar(&targetSpecs, "target", nil, "Source and organization to fetch repositories from as source:organization, may be repeated (overrides --source and --organization)")
+	rootCmd.PersistentFlags().StringVarP(&source, "source", "s", "github", "Source platform (github, bitbucket, bitbucket-server, gitlab, gitea/forgejo, azuredevops, manifest or local)")
+	rootCmd.PersistentFlags().StringVar(&layoutName, "layout", "owner", "Directory layout of the baseline (owner: owner/name, host: host/owner/name)")
+	rootCmd.PersistentFlags().StringVar(&layoutTemplate, "layout-template", "", "Path template for the directory layout using {host}, {source}, {owner} and {name} (overrides --layout)")
+
+	// Storage mode, used by clone and update
+	rootCmd.PersistentFlags().StringVar(&storageMode, "mode", "checkout", "How repositories are stored (checkout: working tree of the default branch, mirror: every branch and tag)")
+
+	// Repository filters, used by discover, clone and update
+	rootCmd.PersistentFlags().BoolVar(&excludeArchived, "exclude-archived", false, "Exclude repositories archived in the source")
+	rootCmd.PersistentFlags().BoolVar(&excludeForks, "exclude-forks", false, "Exclude repositories that are forks of other repositories")
+	rootCmd.PersistentFlags().BoolVar(&excludeDisabled, "exclude-disabled", false, "Exclude repositories the source has disabled")
+	rootCmd.PersistentFlags().BoolVar(&skipEmpty, "skip-empty", false, "Exclude repositories without commits")
+	rootCmd.PersistentFlags().StringArrayVar(&includePatterns, "include", nil, "Include only repositories matching a glob, or a regular expression prefixed with re:, may be repeated")
+	rootCmd.PersistentFlags().StringArrayVar(&excludePatterns, "exclude", nil, "Exclude repositories matching a glob, or a regular expression prefixed with re:, may be repeated")
+	rootCmd.PersistentFlags().StringArrayVar(&languages, "language", nil, "Include only repositories with the primary language, may be repeated")
+	rootCmd.PersistentFlags().StringArrayVar(&topics, "topic", nil, "Include only repositories with the topic, may be repeated")
+	rootCmd.PersistentFlags().StringVar(&visibility, "visibility", "", "Include only public or private repositories")
+	rootCmd.PersistentFlags().StringVar(&updatedSince, "updated-since", "", "Include only repositories updated since a date (2006-01-02) or period (30d, 12w, 6m, 2y)")
+	rootCmd.PersistentFlags().StringVar(&updatedBefore, "updated-before", "", "Include only repositories not updated since a date (2006-01-02) or period (30d, 12w, 6m, 2y)")
 
-	// Flags specific to clone and update commands
+	// Concurrency of the commands working on several repositories
 	rootCmd.PersistentFlags().IntVarP(&threads, "threads", "t", 4, "Number of concurrent threads for cloning/updating repositories")
 }
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jonasbn/baseline/internal/catalog"
+	"github.com/jonasbn/baseline/internal/search"
+	"github.com/jonasbn/baseline/internal/types"
+	"github.com/jonasbn/baseline/internal/worker"
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchLiteral    bool
+	searchIgnoreCase bool
+	searchContext    int
+	searchOwners     []string
+	searchRepos      []string
+	searchPaths      []string
+	searchTypes      []string
+	searchUseIndex   bool
+	searchHistory    bool
+	searchSince      string
+)
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search <pattern>",
+	Short: "Search the files of all repositories in the baseline",
+	Long: `Search the repositories in the baseline directory for a regular expression, or a literal
+string with --fixed-strings, and print the matching lines as owner/repo:path:line: text.
+
+Checkouts are searched in the working tree, skipping files ignored by .gitignore, mirrors
+in the tree of their HEAD. Binary files are skipped. The search works on the baseline
+alone, no source is contacted; select repositories with --owner and --repo and files
+with --path and --type. With --index the trigram indexes built by the index command,
+or clone and update with --index, narrow down the files read.
+
+With --history the tips of all branches and tags are searched with git grep instead of
+the checked out files, with --since every commit after the date. Lines found in several
+revisions are reported once, with the oldest and newest commit containing them.
+
+Example: baseline search -i 'todo|fixme' --owner myorg --type go -C 2`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		opts := search.Options{
+			Pattern:    args[0],
+			Literal:    searchLiteral,
+			IgnoreCase: searchIgnoreCase,
+			Context:    searchContext,
+			Paths:      searchPaths,
+			Types:      searchTypes,
+			History:    searchHistory,
+			Since:      searchSince,
+		}
+		if searchUseIndex {
+			opts.IndexRoot = directory
+		}
+		searcher, err := search.New(opts)
+		if err != nil {
+			return err
+		}
+
+		entries, err := catalog.Scan(directory)
+		if err != nil {
+			return err
+		}
+
+		entries, err = catalog.Select(entries, searchOwners, searchRepos)
+		if err != nil {
+			return err
+		}
+		repositories := baselineRepositories(entries)
+
+		if len(repositories) == 0 {
+			return fmt.Errorf("no repositories to search in %s", directory)
+		}
+
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Searching %d repositories with %d threads\n", len(repositories), threads)
+		}
+
+		wp := worker.NewWorkerPool(threads, verbose)
+		resultChan := wp.SearchRepositories(ctx, repositories, searcher)
+
+		printer := &searchPrinter{separate: searchContext > 0}
+		var matches, files, matchedRepos, indexed, failed int
+		for result := range resultChan {
+			// Errors and the summary go to stderr, so the matches can be piped
+			if result.Error != nil {
+				fmt.Fprintf(os.Stderr, "❌ %s: %v\n", result.Repository.FullName, result.Error)
+				failed++
+				continue
+			}
+
+			if result.Indexed {
+				indexed++
+			}
+			if len(result.Files) == 0 && len(result.Hits) == 0 {
+				continue
+			}
+
+			printer.print(result)
+			matches += result.Matches()
+			files += len(result.Files)
+			matchedRepos++
+		}
+
+		if verbose {
+			fmt.Fprintf(os.Stderr, "\nSearch Summary:\n")
+			fmt.Fprintf(os.Stderr, "  Matches:      %d\n", matches)
+			fmt.Fprintf(os.Stderr, "  Files:        %d\n", files)
+			fmt.Fprintf(os.Stderr, "  Repositories: %d of %d\n", matchedRepos, len(repositories))
+			if searchUseIndex {
+				fmt.Fprintf(os.Stderr, "  Indexed:      %d of %d\n", indexed, len(repositories))
+			}
+			fmt.Fprintf(os.Stderr, "  Failed:       %d\n", failed)
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d repositories could not be searched", failed)
+		}
+
+		return nil
+	},
+}
+
+// searchPrinter prints search results like grep, matches as name:path:line: text and
+// context as name:path-line- text. With context, groups of lines are separated by --.
+type searchPrinter struct {
+	separate bool
+	printed  bool
+}
+
+// print prints the lines of a repository, and the lines found in its history with the
+// oldest and newest commit containing them
+func (p *searchPrinter) print(result search.Result) {
+	for _, h := range result.Hits {
+		revisions := "1 revision"
+		if h.Revisions != 1 {
+			revisions = fmt.Sprintf("%d revisions", h.Revisions)
+		}
+		fmt.Printf("%s:%s:%d: %s (first %s %s, last %s %s, %s)\n",
+			result.Repository.FullName, h.Path, h.Line, h.Text,
+			shortCommit(h.First.Commit), h.First.Date.Format("2006-01-02"),
+			shortCommit(h.Last.Commit), h.Last.Date.Format("2006-01-02"), revisions)
+	}
+
+	for _, f := range result.Files {
+		// A new file always starts a new group
+		prev := -1
+		for _, l := range f.Lines {
+			if p.separate && p.printed && l.Number != prev+1 {
+				fmt.Println("--")
+			}
+			if l.Match {
+				fmt.Printf("%s:%s:%d: %s\n", result.Repository.FullName, f.Path, l.Number, l.Text)
+			} else {
+				fmt.Printf("%s:%s-%d- %s\n", result.Repository.FullName, f.Path, l.Number, l.Text)
+			}
+			prev = l.Number
+			p.printed = true
+		}
+	}
+}
+
+// shortCommit abbreviates a commit hash for display
+func shortCommit(commit string) string {
+	if len(commit) > 10 {
+		return commit[:10]
+	}
+	return commit
+}
+
+// baselineRepositories returns the repositories found in the baseline directory, sorted,
+// named by their path in the baseline, which is owner/name with the default layout
+func baselineRepositories(entries []catalog.Entry) []types.Repository {
+	repositories := make([]types.Repository, len(entries))
+	for i, e := range entries {
+		repositories[i] = e.Repository
+		repositories[i].FullName = e.Path
+	}
+	return repositories
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	searchCmd.Flags().BoolVarP(&searchLiteral, "fixed-strings", "F", false, "Treat the pattern as a literal string instead of a regular expression")
+	searchCmd.Flags().BoolVarP(&searchIgnoreCase, "ignore-case", "i", false, "Ignore case when matching")
+	searchCmd.Flags().IntVarP(&searchContext, "context", "C", 0, "Number of lines to show before and after each match")
+	searchCmd.Flags().StringArrayVar(&searchOwners, "owner", nil, "Search only repositories of the owner, may be repeated")
+	searchCmd.Flags().StringArrayVar(&searchRepos, "repo", nil, "Search only repositories matching a glob, or a regular expression prefixed with re:, may be repeated")
+	searchCmd.Flags().StringArrayVar(&searchPaths, "path", nil, "Search only files matching a glob, e.g. '*.go' or 'docs/*.md', may be repeated")
+	searchCmd.Flags().BoolVar(&searchUseIndex, "index", false, "Read only the files the search index marks as possible matches, repositories without an up-to-date index are searched in full")
+	searchCmd.Flags().BoolVar(&searchHistory, "history", false, "Search the tips of all branches and tags with git grep instead of the checked out files")
+	searchCmd.Flags().StringVar(&searchSince, "since", "", "Search every commit made after the date, e.g. 2023-01-01 or '2 years ago' (implies --history)")
+	searchCmd.Flags().StringArrayVar(&searchTypes, "type", nil, fmt.Sprintf("Search only files of the language (%s), may be repeated", strings.Join(search.LanguageNames(), ", ")))
+}
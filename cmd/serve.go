@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/jonasbn/baseline/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddress        string
+	serveRequestTimeout time.Duration
+	serveMaxResults     int
+	serveMaxSearches    int
+	serveUseIndex       bool
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the baseline over HTTP with a JSON API and a web UI",
+	Long: `Serve the repositories in the baseline directory over HTTP, read-only. The web UI at /
+searches the baseline and shows files, the JSON API offers the same to other tools:
+
+  GET /api/repositories  the repositories with the metadata recorded by clone and update
+                         (owner and repo select repositories as with search)
+  GET /api/search        search, with q, fixed, ignore_case, context, owner, repo, path,
+                         type, history and since as the flags of the search command
+  GET /api/file          a file, with repo the path of the repository in the baseline,
+                         path the file and optionally rev a commit, HEAD by default
+
+Only GET requests are accepted and nothing in the baseline is changed. Searches stop
+after --request-timeout and --max-results matching lines and report what was found,
+the Git processes of a search are stopped with it. At most --max-searches searches run
+at the same time, further search requests are answered with 503.
+The server listens on 127.0.0.1 unless --addr names another address; it has no
+authentication, expose it with care.
+
+Example: baseline serve --addr :8080 --index`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if _, err := os.Stat(directory); err != nil {
+			return fmt.Errorf("baseline directory %s: %w", directory, err)
+		}
+
+		srv := server.New(server.Options{
+			Root:           directory,
+			Address:        serveAddress,
+			Threads:        threads,
+			RequestTimeout: serveRequestTimeout,
+			MaxResults:     serveMaxResults,
+			MaxSearches:    serveMaxSearches,
+			UseIndex:       serveUseIndex,
+			Verbose:        verbose,
+		})
+
+		fmt.Printf("Serving %s on http://%s (press Ctrl+C to stop)\n", directory, serveAddress)
+		if err := srv.ListenAndServe(ctx); err != nil {
+			return err
+		}
+
+		fmt.Println("Server stopped")
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveAddress, "addr", server.DefaultAddress, "Address to listen on, host:port")
+	serveCmd.Flags().DurationVar(&serveRequestTimeout, "request-timeout", 30*time.Second, "Maximum time spent on a request")
+	serveCmd.Flags().IntVar(&serveMaxResults, "max-results", 1000, "Maximum number of matching lines returned by a search")
+	serveCmd.Flags().IntVar(&serveMaxSearches, "max-searches", 4, "Maximum number of searches run at the same time, further searches are refused")
+	serveCmd.Flags().BoolVar(&serveUseIndex, "index", false, "Use the search indexes to narrow down the files read")
+}
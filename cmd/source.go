@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jonasbn/baseline/internal/sources/azuredevops"
+	"github.com/jonasbn/baseline/internal/sources/bitbucket"
+	"github.com/jonasbn/baseline/internal/sources/bitbucketserver"
+	"github.com/jonasbn/baseline/internal/sources/gitea"
+	"github.com/jonasbn/baseline/internal/sources/github"
+	"github.com/jonasbn/baseline/internal/sources/gitlab"
+	"github.com/jonasbn/baseline/internal/sources/local"
+	"github.com/jonasbn/baseline/internal/sources/manifest"
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// supportedSources lists the values accepted by the --source flag
+const supportedSources = "github, bitbucket, bitbucket-server, gitlab, gitea, forgejo, azuredevops, manifest, local"
+
+// newSourceClient creates the repository source client for the given source name
+func newSourceClient(name string) (types.RepositorySource, error) {
+	switch name {
+	case "github":
+		if githubAPIURL != github.DefaultAPIURL || githubCACert != "" {
+			return github.NewGitHubEnterpriseClient(githubToken, githubAPIURL, githubCACert)
+		}
+		return github.NewGitHubClient(githubToken), nil
+	case "bitbucket":
+		return bitbucket.NewBitbucketClient(bitbucketUser, bitbucketToken, verbose), nil
+	case "bitbucket-server":
+		return bitbucketserver.NewBitbucketServerClient(bitbucketServerURL, bitbucketServerToken, verbose)
+	case "gitlab":
+		return gitlab.NewGitLabClient(gitlabURL, gitlabToken), nil
+	case "gitea", "forgejo":
+		return gitea.NewGiteaClient(giteaURL, giteaToken)
+	case "azuredevops":
+		return azuredevops.NewAzureDevOpsClient(azureDevOpsURL, azureDevOpsToken), nil
+	case "manifest":
+		return manifest.NewManifestClient(manifestFile)
+	case "local":
+		return local.NewLocalClient(localRoot, localOffline, verbose)
+	default:
+		return nil, fmt.Errorf("unsupported source: %s (supported: %s)", name, supportedSources)
+	}
+}
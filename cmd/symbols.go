@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jonasbn/baseline/internal/catalog"
+	"github.com/jonasbn/baseline/internal/search"
+	"github.com/jonasbn/baseline/internal/symbols"
+	"github.com/jonasbn/baseline/internal/types"
+	"github.com/jonasbn/baseline/internal/worker"
+	"github.com/spf13/cobra"
+)
+
+var (
+	buildSymbols      bool
+	symbolsRefresh    bool
+	symbolsIgnoreCase bool
+	symbolsKinds      []string
+	symbolsTypes      []string
+	symbolsOwners     []string
+	symbolsRepos      []string
+)
+
+// symbolsCmd represents the symbols command
+var symbolsCmd = &cobra.Command{
+	Use:   "symbols [name]",
+	Short: "Find where symbols are defined in the repositories of the baseline",
+	Long: `Find the definitions of a symbol, such as a function, type or constant, across the
+repositories in the baseline directory and print them as owner/repo:path:line: kind name.
+
+The name is an exact name, a glob such as '*Handler', or a regular expression prefixed
+with re:. Select definitions with --kind, using the kinds of universal-ctags such as
+func, struct, interface, member, class or method, and files with --type.
+
+The definitions are read from the symbol database in .baseline/symbols below the
+baseline directory, built with --refresh. Symbols are extracted with universal-ctags
+when it is installed, otherwise Go files are parsed with the built-in Go parser. Once
+the database exists, clone and update keep it current for the repositories they touch;
+clone --symbols and update --symbols create it.
+
+Example: baseline symbols 'Foo*Handler' --kind func --type go`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		if len(args) == 0 && !symbolsRefresh {
+			return fmt.Errorf("a symbol name is required unless --refresh is given")
+		}
+
+		var query *symbols.Query
+		if len(args) == 1 {
+			var err error
+			query, err = symbols.NewQuery(symbols.Options{
+				Name:       args[0],
+				IgnoreCase: symbolsIgnoreCase,
+				Kinds:      symbolsKinds,
+				Types:      symbolsTypes,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		entries, err := catalog.Scan(directory)
+		if err != nil {
+			return err
+		}
+		entries, err = catalog.Select(entries, symbolsOwners, symbolsRepos)
+		if err != nil {
+			return err
+		}
+		repositories := baselineRepositories(entries)
+		if len(repositories) == 0 {
+			return fmt.Errorf("no repositories in %s", directory)
+		}
+
+		if symbolsRefresh {
+			fmt.Printf("Refreshing the symbols of %d repositories with %s\n", len(repositories), symbols.Tool())
+			wp := worker.NewWorkerPool(threads, verbose)
+			refreshed, failed := refreshSymbols(ctx, wp, repositories)
+
+			fmt.Printf("\nSymbols Summary:\n")
+			fmt.Printf("  Refreshed:  %d\n", refreshed)
+			fmt.Printf("  Unchanged:  %d\n", len(repositories)-refreshed-failed)
+			fmt.Printf("  Failed:     %d\n", failed)
+
+			if failed > 0 {
+				return fmt.Errorf("some repositories failed to refresh symbols")
+			}
+		}
+
+		if query == nil {
+			return nil
+		}
+		if symbolsRefresh {
+			fmt.Println()
+		}
+
+		var found, missing int
+		for _, repo := range repositories {
+			definitions, err := symbols.Find(directory, repo.BaselinePath, query)
+			if errors.Is(err, os.ErrNotExist) {
+				missing++
+				continue
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "❌ %s: %v\n", repo.FullName, err)
+				continue
+			}
+			for _, d := range definitions {
+				printDefinition(repo, d)
+			}
+			found += len(definitions)
+		}
+
+		if missing > 0 {
+			fmt.Fprintf(os.Stderr, "⚠️  %d of %d repositories have no symbols, run baseline symbols --refresh\n", missing, len(repositories))
+		}
+		if verbose {
+			fmt.Fprintf(os.Stderr, "\nFound %d definitions in %d repositories\n", found, len(repositories)-missing)
+		}
+
+		return nil
+	},
+}
+
+// printDefinition prints a definition as name:path:line: kind scope.name (language)
+func printDefinition(repo types.Repository, d symbols.Definition) {
+	name := d.Name
+	if d.Scope != "" {
+		name = d.Scope + "." + d.Name
+	}
+	fmt.Printf("%s:%s:%d: %s %s (%s)\n", repo.FullName, d.Path, d.Line, d.Kind, name, d.Language)
+}
+
+// refreshSymbols refreshes the symbol tables of the repositories, stored at their BaselinePath,
+// and returns the number of tables changed and failed
+func refreshSymbols(ctx context.Context, wp *worker.WorkerPool, repositories []types.Repository) (int, int) {
+	var refreshed, failed int
+	for result := range wp.SymbolRepositories(ctx, repositories, directory) {
+		switch {
+		case result.Error != nil:
+			// Reported also without --verbose, the symbols of the repository are outdated
+			fmt.Printf("⚠️  %s: symbols: %v\n", result.Repository.FullName, result.Error)
+			failed++
+		case result.Rebuilt || result.Changed > 0:
+			if verbose {
+				fmt.Printf("🏷️  %s: read %d files with %s (%.2fs)\n", result.Repository.FullName, result.Changed, result.Tool, result.Duration.Seconds())
+			}
+			refreshed++
+		}
+	}
+	return refreshed, failed
+}
+
+// keepSymbols reports whether clone and update refresh the symbols of the repositories
+// they touch: when asked to, or when the baseline has a symbol database
+func keepSymbols() bool {
+	return buildSymbols || symbols.Store.Exists(directory)
+}
+
+// addSymbolsFlag adds the symbols flag shared by clone and update
+func addSymbolsFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&buildSymbols, "symbols", false, "Build or refresh the symbol database of the repositories, see the symbols command")
+}
+
+func init() {
+	rootCmd.AddCommand(symbolsCmd)
+
+	symbolsCmd.Flags().BoolVar(&symbolsRefresh, "refresh", false, "Build or refresh the symbol database of the repositories before searching")
+	symbolsCmd.Flags().BoolVarP(&symbolsIgnoreCase, "ignore-case", "i", false, "Ignore case when matching names")
+	symbolsCmd.Flags().StringArrayVar(&symbolsKinds, "kind", nil, "Find only symbols of the kind, e.g. func, struct or class, may be repeated")
+	symbolsCmd.Flags().StringArrayVar(&symbolsTypes, "type", nil, fmt.Sprintf("Find only symbols in files of the language (%s), may be repeated", strings.Join(search.LanguageNames(), ", ")))
+	symbolsCmd.Flags().StringArrayVar(&symbolsOwners, "owner", nil, "Find only symbols in repositories of the owner, may be repeated")
+	symbolsCmd.Flags().StringArrayVar(&symbolsRepos, "repo", nil, "Find only symbols in repositories matching a glob, or a regular expression prefixed with re:, may be repeated")
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// target is a (source, organization) pair to fetch repositories from
+type target struct {
+	Source       string
+	Organization string
+}
+
+// String returns the label used for the target in output, e.g. github/acme
+func (t target) String() string {
+	if t.Organization == "" {
+		return t.Source
+	}
+	return t.Source + "/" + t.Organization
+}
+
+// targetRepositories holds the repositories fetched for a single target
+type targetRepositories struct {
+	target       target
+	repositories []types.Repository
+}
+
+// resolveTargets returns the targets of the run. Each --target flag is given as
+// source:organization, without any --target flags --source and --organization are used.
+func resolveTargets() ([]target, error) {
+	if len(targetSpecs) == 0 {
+		return []target{{Source: source, Organization: organization}}, nil
+	}
+
+	var targets []target
+	seen := make(map[target]bool)
+
+	for _, spec := range targetSpecs {
+		// The organization may contain slashes (GitLab subgroups, Azure DevOps projects),
+		// so only the first colon separates it from the source
+		src, org, _ := strings.Cut(spec, ":")
+		if src == "" {
+			return nil, fmt.Errorf("invalid target %q (expected source:organization)", spec)
+		}
+
+		t := target{Source: src, Organization: org}
+		if !seen[t] {
+			seen[t] = true
+			targets = append(targets, t)
+		}
+	}
+
+	return targets, nil
+}
+
+// fetchTargets fetches the repositories of all targets concurrently. The clients of
+// all targets are created first, so no fetch is started if one of them fails.
+func fetchTargets(ctx context.Context, targets []target) ([]targetRepositories, error) {
+	clients := make([]types.RepositorySource, len(targets))
+	for i, t := range targets {
+		sourceClient, err := newSourceClient(t.Source)
+		if err != nil {
+			return nil, err
+		}
+		clients[i] = sourceClient
+	}
+
+	results := make([]targetRepositories, len(targets))
+	errs := make([]error, len(targets))
+
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t target, sourceClient types.RepositorySource) {
+			defer wg.Done()
+
+			repositories, err := sourceClient.GetRepositories(ctx, t.Organization)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", t, err)
+				return
+			}
+
+			for j := range repositories {
+				repositories[j].Source = sourceClient.GetName()
+			}
+			results[i] = targetRepositories{target: t, repositories: repositories}
+		}(i, t, clients[i])
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("failed to fetch repositories: %w", err)
+	}
+
+	return results, nil
+}
+
+// mergeRepositories merges the repositories of all targets, dropping repositories
+// listed by more than one target. The returned map holds the target label of each
+// repository, keyed by repositoryKey.
+func mergeRepositories(results []targetRepositories) ([]types.Repository, map[string]string) {
+	var repositories []types.Repository
+	labels := make(map[string]string)
+
+	for _, result := range results {
+		for _, repo := range result.repositories {
+			key := repositoryKey(repo)
+			if _, ok := labels[key]; ok {
+				continue
+			}
+
+			labels[key] = result.target.String()
+			repositories = append(repositories, repo)
+		}
+	}
+
+	return repositories, labels
+}
+
+// repositoryKey identifies a repository across sources
+func repositoryKey(repo types.Repository) string {
+	return repo.Source + ":" + repo.FullName
+}
+
+// useSSHURLs replaces the HTTP(S) clone URLs with SSH URLs where available. Other
+// clone URLs, such as the paths of existing clones adopted with --local-offline,
+// are kept.
+func useSSHURLs(repositories []types.Repository) {
+	for i := range repositories {
+		if !isHTTPURL(repositories[i].CloneURL) {
+			continue
+		}
+
+		if repositories[i].SSHURL != "" {
+			repositories[i].CloneURL = repositories[i].SSHURL
+			if verbose {
+				fmt.Printf("Using SSH URL for %s: %s\n", repositories[i].FullName, repositories[i].SSHURL)
+			}
+		} else if verbose {
+			fmt.Printf("Warning: No SSH URL available for %s, using HTTPS\n", repositories[i].FullName)
+		}
+	}
+}
+
+// isHTTPURL reports whether the clone URL is an HTTP or HTTPS URL
+func isHTTPURL(u string) bool {
+	return strings.HasPrefix(u, "https://") || strings.HasPrefix(u, "http://")
+}
+
+// summary counts the outcome of clone and update operations. Repositories needing
+// attention after an update are counted apart from the successful ones.
+type summary struct {
+	Successful int
+	Updated    int
+	Attention  int
+	Skipped    int
+	Failed     int
+}
+
+// printBreakdown prints the summary of each target, if the run covered more than one
+func printBreakdown(targets []target, breakdown map[string]*summary, withUpdated bool) {
+	if len(targets) < 2 {
+		return
+	}
+
+	labels := make([]string, 0, len(targets))
+	for _, t := range targets {
+		labels = append(labels, t.String())
+	}
+	sort.Strings(labels)
+
+	fmt.Printf("\nBy source:\n")
+	for _, label := range labels {
+		s, ok := breakdown[label]
+		if !ok {
+			s = &summary{}
+		}
+
+		if withUpdated {
+			fmt.Printf("  %-30s successful: %d, updated: %d, attention: %d, skipped: %d, failed: %d\n", label, s.Successful, s.Updated, s.Attention, s.Skipped, s.Failed)
+		} else {
+			fmt.Printf("  %-30s successful: %d, skipped: %d, failed: %d\n", label, s.Successful, s.Skipped, s.Failed)
+		}
+	}
+}
@@ -4,45 +4,78 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/jonasbn/baseline/internal/sources/bitbucket"
-	"github.com/jonasbn/baseline/internal/sources/github"
+	"github.com/jonasbn/baseline/internal/catalog"
+	"github.com/jonasbn/baseline/internal/prune"
 	"github.com/jonasbn/baseline/internal/types"
 	"github.com/jonasbn/baseline/internal/worker"
 	"github.com/spf13/cobra"
 )
 
 var (
-	updateUseSSH bool
+	updateUseSSH      bool
+	updatePrune       bool
+	updatePruneAction string
+	updatePruneDryRun bool
 )
 
 // updateCmd represents the update command
 var updateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Update repositories in the target directory from the specified source",
-	Long: `Update repositories in the target directory from the specified source (GitHub or Bitbucket).
+	Long: `Update repositories in the target directory from the specified source (GitHub, Bitbucket, GitLab, Gitea or Azure DevOps).
 
-This command fetches the latest changes for existing repositories in the baseline directory.
-Only repositories that already exist locally will be updated.
+This command fetches the latest changes for existing repositories in the baseline directory
+and fast-forwards the checked-out branch to its upstream. Only repositories that already
+exist locally will be updated.
+
+Local commits are never discarded: repositories whose branch is ahead of or has diverged
+from its upstream, is detached or has no upstream are left as they are and listed as
+needing attention. With --mode mirror all branches and tags are updated and refs deleted
+upstream are pruned.
+
+Sparse checkout patterns given with --sparse or --sparse-rule are re-applied when they
+differ from the patterns of the last run. Repositories none of them applies to keep their
+patterns, a matching rule with "full" restores the full working tree.
+
+Use --submodules to update submodules recursively. Failing submodules are reported
+separately and do not fail the repository.
+
+Use --prune to also list repositories no longer returned by the source, and
+--prune-action archive or delete to move them to the archive or remove them. As with the
+prune command this is a dry run until --prune-dry-run=false is given. Nothing is pruned
+after a run in which repositories failed to update.
+
+Use --lfs to select how Git LFS content is handled, see the clone command.
+
+Repositories stored in the other mode are reported as failures, use --convert-mode to
+replace them with a fresh clone in the selected mode.
 
 Use the --ssh flag to update using SSH URLs instead of HTTPS URLs, which is useful 
-when you have SSH keys configured and want to avoid HTTPS authentication issues.`,
+when you have SSH keys configured and want to avoid HTTPS authentication issues.
+
+Use the --target flag, repeatedly, to update from several sources and organizations
+in one run, e.g. --target github:acme --target bitbucket:widgets.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
 
-		// Create the appropriate source client
-		var sourceClient types.RepositorySource
+		var pruneAction prune.Action
+		if updatePrune {
+			action, err := prune.ParseAction(updatePruneAction)
+			if err != nil {
+				return err
+			}
+			pruneAction = action
+		}
 
-		switch source {
-		case "github":
-			sourceClient = github.NewGitHubClient(githubToken)
-		case "bitbucket":
-			sourceClient = bitbucket.NewBitbucketClient(bitbucketUser, bitbucketToken, verbose)
-		default:
-			return fmt.Errorf("unsupported source: %s (supported: github, bitbucket)", source)
+		targets, err := resolveTargets()
+		if err != nil {
+			return err
 		}
 
 		if verbose {
-			fmt.Printf("Updating repositories from %s for organization: %s\n", source, organization)
+			for _, t := range targets {
+				fmt.Printf("Updating repositories from %s for organization: %s\n", t.Source, t.Organization)
+			}
 			fmt.Printf("Target directory: %s\n", directory)
 			fmt.Printf("Using %d concurrent threads\n", threads)
 			if updateUseSSH {
@@ -52,68 +85,159 @@ when you have SSH keys configured and want to avoid HTTPS authentication issues.
 			}
 		}
 
-		// Fetch repositories
-		repositories, err := sourceClient.GetRepositories(ctx, organization)
+		// Validate the Git options and filters before contacting any source
+		gitOptions, err := newGitOptions()
+		if err != nil {
+			return err
+		}
+		repoFilter, err := newFilter()
 		if err != nil {
-			return fmt.Errorf("failed to fetch repositories: %w", err)
+			return err
+		}
+
+		// Fetch repositories from all targets
+		fetched, err := fetchTargets(ctx, targets)
+		if err != nil {
+			return err
+		}
+		results, excluded := filterTargets(repoFilter, fetched)
+		printExclusions(excluded)
+		repositories, labels := mergeRepositories(results)
+
+		if err := checkCollisions(repositories, gitOptions.Layout); err != nil {
+			return err
 		}
 
 		// Convert to SSH URLs if --ssh flag is set
 		if updateUseSSH {
-			for i := range repositories {
-				if repositories[i].SSHURL != "" {
-					repositories[i].CloneURL = repositories[i].SSHURL
-					if verbose {
-						fmt.Printf("Using SSH URL for %s: %s\n", repositories[i].FullName, repositories[i].SSHURL)
-					}
-				} else if verbose {
-					fmt.Printf("Warning: No SSH URL available for %s, using HTTPS\n", repositories[i].FullName)
-				}
-			}
+			useSSHURLs(repositories)
+		}
+
+		// Move clones of renamed repositories before looking for existing clones
+		if err := relocateRenamed(repositories, gitOptions); err != nil {
+			return err
 		}
 
 		fmt.Printf("Found %d repositories to check for updates\n", len(repositories))
 
 		// Create worker pool and start updating
-		wp := worker.NewWorkerPool(threads, verbose)
+		wp := worker.NewWorkerPoolWithOptions(threads, verbose, gitOptions)
 		resultChan := wp.UpdateRepositories(ctx, repositories, directory)
 
 		// Process results
-		var successful, failed, skipped, updated int
+		var total summary
+		var submoduleFailures int
+		var lfsFailures int
+		var present []types.Repository
+		breakdown := make(map[string]*summary)
 		for result := range resultChan {
+			label := labels[repositoryKey(result.Repository)]
+			if breakdown[label] == nil {
+				breakdown[label] = &summary{}
+			}
+			counts := []*summary{&total, breakdown[label]}
+
 			if result.Error != nil {
 				if verbose {
 					fmt.Printf("❌ %s: %v\n", result.Repository.FullName, result.Error)
 				}
-				failed++
+				for _, c := range counts {
+					c.Failed++
+				}
 			} else if !result.Success {
 				if verbose {
 					fmt.Printf("⏭️  %s: does not exist locally\n", result.Repository.FullName)
 				}
-				skipped++
+				for _, c := range counts {
+					c.Skipped++
+				}
 			} else {
+				present = append(present, result.Repository)
+				if printSubmoduleError(result.Repository, result.SubmoduleError) {
+					submoduleFailures++
+				}
+				if printLFSError(result.Repository, result.LFSError) {
+					lfsFailures++
+				}
+				if needsAttention(result.State) {
+					// Reported also without --verbose, the working tree was not advanced
+					fmt.Printf("⚠️  %s: %s, not fast-forwarded\n", result.Repository.FullName, result.State)
+					for _, c := range counts {
+						c.Attention++
+					}
+					continue
+				}
+
 				if result.Updated {
 					if verbose {
 						fmt.Printf("🔄 %s: updated (%.2fs)\n", result.Repository.FullName, result.Duration.Seconds())
 					}
-					updated++
+					for _, c := range counts {
+						c.Updated++
+					}
 				} else {
 					if verbose {
 						fmt.Printf("✅ %s: up to date (%.2fs)\n", result.Repository.FullName, result.Duration.Seconds())
 					}
 				}
-				successful++
+				for _, c := range counts {
+					c.Successful++
+				}
 			}
 		}
 
+		// Keep the metadata of the repositories for the commands working on the baseline alone
+		located := inBaseline(present, gitOptions.Layout)
+		if err := catalog.Record(directory, located); err != nil {
+			fmt.Printf("⚠️  Failed to record repository metadata: %v\n", err)
+		}
+
+		indexEnabled := keepIndexes()
+		var indexed, indexFailures int
+		if indexEnabled {
+			indexed, _, indexFailures = refreshIndexes(ctx, wp, located)
+		}
+		symbolsEnabled := keepSymbols()
+		var symbolsRefreshed, symbolsFailures int
+		if symbolsEnabled {
+			symbolsRefreshed, symbolsFailures = refreshSymbols(ctx, wp, located)
+		}
+
 		// Print summary
 		fmt.Printf("\nUpdate Summary:\n")
-		fmt.Printf("  Successful: %d\n", successful)
-		fmt.Printf("  Updated:    %d\n", updated)
-		fmt.Printf("  Skipped:    %d (not found locally)\n", skipped)
-		fmt.Printf("  Failed:     %d\n", failed)
+		fmt.Printf("  Successful: %d\n", total.Successful)
+		fmt.Printf("  Updated:    %d\n", total.Updated)
+		fmt.Printf("  Skipped:    %d (not found locally)\n", total.Skipped)
+		fmt.Printf("  Failed:     %d\n", total.Failed)
+		if submoduleFailures > 0 {
+			fmt.Printf("  Submodules: %d failed\n", submoduleFailures)
+		}
+		if lfsFailures > 0 {
+			fmt.Printf("  LFS:        %d failed\n", lfsFailures)
+		}
+		if indexEnabled {
+			fmt.Printf("  Indexed:    %d (%d failed)\n", indexed, indexFailures)
+		}
+		if symbolsEnabled {
+			fmt.Printf("  Symbols:    %d (%d failed)\n", symbolsRefreshed, symbolsFailures)
+		}
+		if total.Attention > 0 {
+			fmt.Printf("  Attention:  %d (not fast-forwarded)\n", total.Attention)
+		}
+		printBreakdown(targets, breakdown, true)
+
+		// Look for repositories removed upstream using the repositories fetched for the
+		// update, repositories excluded by filters still exist and are no orphans
+		if updatePrune {
+			fmt.Println()
+			if total.Failed > 0 {
+				fmt.Printf("⚠️  Not pruning, %d repositories failed to update\n", total.Failed)
+			} else if err := runPrune(fetched, gitOptions.Layout, pruneAction, updatePruneDryRun); err != nil {
+				return err
+			}
+		}
 
-		if failed > 0 {
+		if total.Failed > 0 {
 			return fmt.Errorf("some repositories failed to update")
 		}
 
@@ -121,7 +245,25 @@ when you have SSH keys configured and want to avoid HTTPS authentication issues.
 	},
 }
 
+// needsAttention reports whether the checked-out branch could not be fast-forwarded
+func needsAttention(state types.UpdateState) bool {
+	switch state {
+	case types.UpdateStateAhead, types.UpdateStateDiverged, types.UpdateStateDetached, types.UpdateStateNoUpstream:
+		return true
+	}
+	return false
+}
+
 func init() {
 	rootCmd.AddCommand(updateCmd)
+	updateCmd.Flags().BoolVar(&updatePrune, "prune", false, "Also list repositories no longer returned by the source, see the prune command")
+	updateCmd.Flags().StringVar(&updatePruneAction, "prune-action", string(prune.ActionReport), "What --prune does with orphaned repositories: report, archive or delete")
+	updateCmd.Flags().BoolVar(&updatePruneDryRun, "prune-dry-run", true, "Show what --prune would archive or delete without changing anything")
+	addSparseFlags(updateCmd)
+	addSubmoduleFlag(updateCmd)
+	addIndexFlag(updateCmd)
+	addSymbolsFlag(updateCmd)
+	addLFSFlag(updateCmd)
+	updateCmd.Flags().BoolVar(&convertMode, "convert-mode", false, "Replace repositories stored in the other mode with a fresh clone in the selected --mode")
 	updateCmd.Flags().BoolVar(&updateUseSSH, "ssh", false, "Use SSH URLs for updating instead of HTTPS")
 }
@@ -0,0 +1,210 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jonasbn/baseline/internal/filter"
+	"github.com/jonasbn/baseline/internal/git"
+	"github.com/jonasbn/baseline/internal/remote"
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// File is the name of the catalog in the metadata directory of the baseline
+const File = "repositories.json"
+
+// Catalog maps the path of each repository in the baseline, relative to the baseline
+// directory with forward slashes, to the metadata the source returned for it
+type Catalog map[string]types.Repository
+
+// Entry is a repository found in the baseline
+type Entry struct {
+	// Path is the path in the baseline, relative to the baseline directory with forward slashes
+	Path string
+	// Repository holds the metadata recorded in the catalog, or the owner and name taken
+	// from the origin remote or the path. BaselinePath is the directory of the repository.
+	Repository types.Repository
+}
+
+// catalogPath returns the location of the catalog of the baseline root
+func catalogPath(root string) string {
+	return filepath.Join(root, git.MetadataDir, File)
+}
+
+// Load reads the catalog of the baseline root, a missing catalog is empty
+func Load(root string) (Catalog, error) {
+	data, err := os.ReadFile(catalogPath(root))
+	if errors.Is(err, os.ErrNotExist) {
+		return Catalog{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := Catalog{}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to read catalog %s: %w", catalogPath(root), err)
+	}
+	return c, nil
+}
+
+// Save writes the catalog of the baseline root, replacing it atomically
+func (c Catalog) Save(root string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode catalog: %w", err)
+	}
+
+	file := catalogPath(root)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("failed to create metadata directory: %w", err)
+	}
+
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write catalog %s: %w", file, err)
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write catalog %s: %w", file, err)
+	}
+	return nil
+}
+
+// Record adds the metadata of the repositories, stored at their BaselinePath, to the catalog
+// of the baseline root. Entries of repositories no longer in the baseline are dropped.
+func Record(root string, repositories []types.Repository) error {
+	c, err := Load(root)
+	if err != nil {
+		return err
+	}
+
+	for _, repo := range repositories {
+		rel, err := filepath.Rel(root, repo.BaselinePath)
+		if err != nil {
+			return err
+		}
+		c[filepath.ToSlash(rel)] = repo
+	}
+
+	for rel := range c {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(rel))); errors.Is(err, os.ErrNotExist) {
+			delete(c, rel)
+		}
+	}
+
+	return c.Save(root)
+}
+
+// Move moves the entry of the repository at oldPath to newPath after the repository was
+// moved within the baseline root. Repositories without an entry are moved without error.
+func Move(root, oldPath, newPath string) error {
+	oldRel, err := filepath.Rel(root, oldPath)
+	if err != nil {
+		return err
+	}
+	newRel, err := filepath.Rel(root, newPath)
+	if err != nil {
+		return err
+	}
+
+	c, err := Load(root)
+	if err != nil {
+		return err
+	}
+
+	repo, ok := c[filepath.ToSlash(oldRel)]
+	if !ok {
+		return nil
+	}
+	delete(c, filepath.ToSlash(oldRel))
+	c[filepath.ToSlash(newRel)] = repo
+
+	return c.Save(root)
+}
+
+// Scan returns the repositories found in the baseline root, sorted by path, with the
+// metadata recorded in the catalog. Repositories missing from the catalog are described
+// by their origin remote when it is a hosted repository and by their path otherwise.
+func Scan(root string) ([]Entry, error) {
+	paths, err := git.FindRepositories(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+
+	c, err := Load(root)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(paths))
+	for _, p := range paths {
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(rel)
+
+		repo, ok := c[rel]
+		if !ok {
+			repo = types.Repository{
+				Name:  strings.TrimSuffix(path.Base(rel), ".git"),
+				Owner: path.Dir(rel),
+			}
+			if originURL, err := git.RemoteURL(p, "origin"); err == nil {
+				if r, err := remote.Parse(originURL); err == nil && r.Host != "" && r.Owner != "" {
+					repo.Name = r.Name
+					repo.Owner = r.Owner
+				}
+			}
+			repo.FullName = repo.Owner + "/" + repo.Name
+		}
+		repo.BaselinePath = p
+
+		entries = append(entries, Entry{Path: rel, Repository: repo})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	return entries, nil
+}
+
+// Select keeps the entries of one of the owners, if any, that match one of the patterns,
+// if any. Patterns follow the rules of the include filter.
+func Select(entries []Entry, owners, patterns []string) ([]Entry, error) {
+	f, err := filter.New(filter.Options{Include: patterns})
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []Entry
+	for _, e := range entries {
+		if len(owners) > 0 && !containsFold(owners, e.Repository.Owner) {
+			continue
+		}
+		if _, excluded := f.Excludes(e.Repository); excluded {
+			continue
+		}
+		selected = append(selected, e)
+	}
+
+	return selected, nil
+}
+
+// containsFold reports whether values contains s, ignoring case
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jonasbn/baseline/internal/testutil"
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+func TestRecordAndScan(t *testing.T) {
+	root := t.TempDir()
+	api := filepath.Join(root, "acme", "api")
+	web := filepath.Join(root, "github.com", "other", "web")
+	local := filepath.Join(root, "misc", "tool")
+	testutil.InitRepository(t, api, "")
+	testutil.InitRepository(t, web, "https://github.com/other/web.git")
+	testutil.InitRepository(t, local, "")
+
+	repo := types.Repository{Name: "api", FullName: "acme/api", Owner: "acme", Description: "The API", BaselinePath: api}
+	if err := Record(root, []types.Repository{repo}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	entries, err := Scan(root)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %+v", entries)
+	}
+
+	expected := []struct{ path, fullName, description, baselinePath string }{
+		{"acme/api", "acme/api", "The API", api},
+		{"github.com/other/web", "other/web", "", web},
+		{"misc/tool", "misc/tool", "", local},
+	}
+	for i, e := range expected {
+		got := entries[i]
+		if got.Path != e.path || got.Repository.FullName != e.fullName ||
+			got.Repository.Description != e.description || got.Repository.BaselinePath != e.baselinePath {
+			t.Errorf("Entry %d: expected %+v, got %+v", i, e, got)
+		}
+	}
+
+	selected, err := Select(entries, []string{"OTHER"}, nil)
+	if err != nil || len(selected) != 1 || selected[0].Path != "github.com/other/web" {
+		t.Errorf("Expected other/web, got %+v (%v)", selected, err)
+	}
+
+	// Repositories removed from the baseline are dropped from the catalog
+	if err := os.RemoveAll(api); err != nil {
+		t.Fatal(err)
+	}
+	if err := Record(root, nil); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	c, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(c) != 0 {
+		t.Errorf("Expected an empty catalog, got %v", c)
+	}
+}
+
+func TestMove(t *testing.T) {
+	root := t.TempDir()
+	api := filepath.Join(root, "acme", "api")
+	testutil.InitRepository(t, api, "")
+
+	repo := types.Repository{Name: "api", FullName: "acme/api", Owner: "acme", Description: "The API", BaselinePath: api}
+	if err := Record(root, []types.Repository{repo}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	moved := filepath.Join(root, "github.com", "acme", "api")
+	if err := Move(root, api, moved); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	c, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if _, ok := c["acme/api"]; ok || c["github.com/acme/api"].Description != "The API" {
+		t.Errorf("Expected the entry at the new path, got %v", c)
+	}
+
+	// Repositories without an entry are moved without error
+	if err := Move(root, api, moved); err != nil {
+		t.Errorf("Move of a missing entry failed: %v", err)
+	}
+}
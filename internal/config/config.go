@@ -0,0 +1,162 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BaselineFileName is the name of the per-baseline configuration file,
+// stored in the baseline directory
+const BaselineFileName = ".baseline.yaml"
+
+// File represents a configuration file holding named profiles
+type File struct {
+	Path           string             `yaml:"-"`
+	DefaultProfile string             `yaml:"default_profile"`
+	Profiles       map[string]Profile `yaml:"profiles"`
+}
+
+// Profile maps setting names, which are the long names of the command line
+// flags (e.g. "directory", "threads", "github-token"), to their values
+type Profile map[string]Setting
+
+// Setting is the value of a profile setting. Lists are used for flags that
+// may be repeated, such as "target".
+type Setting struct {
+	Values []string
+	File   string
+	Line   int
+}
+
+// UnmarshalYAML accepts a scalar or a list of scalars
+func (s *Setting) UnmarshalYAML(node *yaml.Node) error {
+	s.Line = node.Line
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		s.Values = []string{node.Value}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: list items must be plain values", item.Line)
+			}
+			s.Values = append(s.Values, item.Value)
+		}
+	default:
+		return fmt.Errorf("line %d: setting must be a value or a list of values", node.Line)
+	}
+
+	return nil
+}
+
+// DefaultPath returns the location of the user configuration file,
+// $XDG_CONFIG_HOME/baseline/config.yaml or ~/.config/baseline/config.yaml
+func DefaultPath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "baseline", "config.yaml"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %w", err)
+	}
+
+	return filepath.Join(home, ".config", "baseline", "config.yaml"), nil
+}
+
+// Load reads a configuration file. A missing file is reported with an error
+// satisfying os.IsNotExist.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := Parse(path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// Parse parses the configuration file content read from path
+func Parse(path string, data []byte) (*File, error) {
+	file := &File{Path: path}
+	if err := yaml.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, profile := range file.Profiles {
+		for key, setting := range profile {
+			setting.File = path
+			profile[key] = setting
+		}
+	}
+
+	return file, nil
+}
+
+// Profile returns the named profile
+func (f *File) Profile(name string) (Profile, bool) {
+	if f == nil {
+		return nil, false
+	}
+	profile, ok := f.Profiles[name]
+	return profile, ok
+}
+
+// Merge returns a profile holding the settings of p, overridden by the settings of override
+func (p Profile) Merge(override Profile) Profile {
+	merged := make(Profile, len(p)+len(override))
+	for key, setting := range p {
+		merged[key] = setting
+	}
+	for key, setting := range override {
+		merged[key] = setting
+	}
+	return merged
+}
+
+// envReference matches ${VAR} references to environment variables
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Expand returns the values of the setting with ${VAR} references replaced by the
+// value of the environment variable and a leading ~/ replaced by the home directory,
+// so tokens do not have to be written into the file
+func (s Setting) Expand() ([]string, error) {
+	values := make([]string, len(s.Values))
+
+	for i, value := range s.Values {
+		var missing []string
+		value = envReference.ReplaceAllStringFunc(value, func(reference string) string {
+			name := envReference.FindStringSubmatch(reference)[1]
+			env, ok := os.LookupEnv(name)
+			if !ok {
+				missing = append(missing, name)
+			}
+			return env
+		})
+
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("%s:%d: environment variable %s is not set", s.File, s.Line, strings.Join(missing, ", "))
+		}
+
+		if value == "~" || strings.HasPrefix(value, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: failed to expand %s: %w", s.File, s.Line, value, err)
+			}
+			value = filepath.Join(home, strings.TrimPrefix(value, "~"))
+		}
+
+		values[i] = value
+	}
+
+	return values, nil
+}
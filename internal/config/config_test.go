@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfig = `default_profile: work
+profiles:
+  work:
+    directory: ~/baseline
+    threads: 8
+    ssh: true
+    github-token: ${BASELINE_TEST_TOKEN}
+    target:
+      - github:acme
+      - bitbucket:widgets
+  oss:
+    organization: jonasbn
+`
+
+func TestParse(t *testing.T) {
+	file, err := Parse("config.yaml", []byte(testConfig))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if file.DefaultProfile != "work" {
+		t.Errorf("Expected default profile 'work', got '%s'", file.DefaultProfile)
+	}
+
+	work, ok := file.Profile("work")
+	if !ok {
+		t.Fatal("Expected profile 'work' to exist")
+	}
+
+	if targets := work["target"].Values; len(targets) != 2 || targets[1] != "bitbucket:widgets" {
+		t.Errorf("Unexpected targets %v", targets)
+	}
+
+	if work["threads"].Line != 5 || work["threads"].File != "config.yaml" {
+		t.Errorf("Expected setting position config.yaml:5, got %s:%d", work["threads"].File, work["threads"].Line)
+	}
+
+	if _, ok := file.Profile("missing"); ok {
+		t.Error("Expected profile 'missing' not to exist")
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := Parse("config.yaml", []byte("profiles:\n  work:\n    target:\n      - source: github\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 4") {
+		t.Errorf("Expected an error pointing at line 4, got %v", err)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	file, err := Parse("config.yaml", []byte(testConfig))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	work, _ := file.Profile("work")
+
+	t.Setenv("BASELINE_TEST_TOKEN", "secret")
+	token, err := work["github-token"].Expand()
+	if err != nil || token[0] != "secret" {
+		t.Errorf("Expected token from environment, got %v (%v)", token, err)
+	}
+
+	home, _ := os.UserHomeDir()
+	dir, err := work["directory"].Expand()
+	if err != nil || dir[0] != filepath.Join(home, "baseline") {
+		t.Errorf("Expected directory in home directory, got %v (%v)", dir, err)
+	}
+
+	os.Unsetenv("BASELINE_TEST_TOKEN")
+	if _, err := work["github-token"].Expand(); err == nil || !strings.Contains(err.Error(), "config.yaml:7") {
+		t.Errorf("Expected an error for an unset environment variable, got %v", err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	user := Profile{"directory": {Values: []string{"~/baseline"}}, "threads": {Values: []string{"4"}}}
+	baseline := Profile{"threads": {Values: []string{"16"}}}
+
+	merged := user.Merge(baseline)
+	if merged["threads"].Values[0] != "16" || merged["directory"].Values[0] != "~/baseline" {
+		t.Errorf("Unexpected merged profile %+v", merged)
+	}
+}
@@ -0,0 +1,282 @@
+package filter
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// Options selects the repositories to include and exclude
+type Options struct {
+	ExcludeArchived bool
+	ExcludeForks    bool
+	ExcludeDisabled bool
+	SkipEmpty       bool
+
+	// Include keeps only repositories matching one of the patterns. A pattern is a
+	// glob, matched against the full name if it contains a slash and against the
+	// name otherwise, or a regular expression prefixed with "re:" matched against
+	// the full name.
+	Include []string
+	// Exclude drops repositories matching one of the patterns, see Include
+	Exclude []string
+	// Languages keeps only repositories with one of the languages, ignoring case
+	Languages []string
+	// Topics keeps only repositories with at least one of the topics, ignoring case
+	Topics []string
+	// Visibility keeps only public or only private repositories
+	Visibility string
+	// UpdatedSince keeps only repositories updated after a date (2006-01-02) or
+	// within a period such as 30d, 12w, 6m or 2y
+	UpdatedSince string
+	// UpdatedBefore keeps only repositories not updated after a date or period
+	UpdatedBefore string
+
+	// Now is the reference time for periods, defaults to the current time
+	Now time.Time
+}
+
+// Exclusion is a repository excluded by a filter, with the reason
+type Exclusion struct {
+	Repository types.Repository
+	Reason     string
+}
+
+// rule excludes the repositories it matches, giving the reason
+type rule func(repo types.Repository) (string, bool)
+
+// Filter decides which repositories take part in a run
+type Filter struct {
+	rules []rule
+}
+
+// New creates a filter from the options
+func New(opts Options) (*Filter, error) {
+	f := &Filter{}
+
+	if opts.ExcludeArchived {
+		f.rules = append(f.rules, func(repo types.Repository) (string, bool) {
+			return "archived", repo.Archived
+		})
+	}
+	if opts.ExcludeForks {
+		f.rules = append(f.rules, func(repo types.Repository) (string, bool) {
+			return "fork", repo.Fork
+		})
+	}
+	if opts.ExcludeDisabled {
+		f.rules = append(f.rules, func(repo types.Repository) (string, bool) {
+			return "disabled", repo.Disabled
+		})
+	}
+	if opts.SkipEmpty {
+		f.rules = append(f.rules, func(repo types.Repository) (string, bool) {
+			return "empty", repo.Empty
+		})
+	}
+
+	if len(opts.Include) > 0 {
+		matchers, err := newMatchers(opts.Include)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern: %w", err)
+		}
+		f.rules = append(f.rules, func(repo types.Repository) (string, bool) {
+			for _, m := range matchers {
+				if m.match(repo) {
+					return "", false
+				}
+			}
+			return "does not match any include pattern", true
+		})
+	}
+
+	if len(opts.Exclude) > 0 {
+		matchers, err := newMatchers(opts.Exclude)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+		}
+		f.rules = append(f.rules, func(repo types.Repository) (string, bool) {
+			for _, m := range matchers {
+				if m.match(repo) {
+					return "matches exclude pattern " + m.pattern, true
+				}
+			}
+			return "", false
+		})
+	}
+
+	// Repositories without a known language are kept, not every source reports it
+	if len(opts.Languages) > 0 {
+		f.rules = append(f.rules, func(repo types.Repository) (string, bool) {
+			if repo.Language == "" {
+				return "", false
+			}
+			for _, language := range opts.Languages {
+				if strings.EqualFold(language, repo.Language) {
+					return "", false
+				}
+			}
+			return "language " + repo.Language, true
+		})
+	}
+
+	if len(opts.Topics) > 0 {
+		f.rules = append(f.rules, func(repo types.Repository) (string, bool) {
+			for _, topic := range opts.Topics {
+				for _, t := range repo.Topics {
+					if strings.EqualFold(topic, t) {
+						return "", false
+					}
+				}
+			}
+			return "no matching topic", true
+		})
+	}
+
+	switch opts.Visibility {
+	case "":
+	case "public", "private":
+		private := opts.Visibility == "private"
+		f.rules = append(f.rules, func(repo types.Repository) (string, bool) {
+			if repo.Private == private {
+				return "", false
+			}
+			if repo.Private {
+				return "private", true
+			}
+			return "public", true
+		})
+	default:
+		return nil, fmt.Errorf("invalid visibility %q, use public or private", opts.Visibility)
+	}
+
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	// Repositories without a known update time are kept, not every source reports it
+	if opts.UpdatedSince != "" {
+		since, err := parseTime(opts.UpdatedSince, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid updated-since: %w", err)
+		}
+		f.rules = append(f.rules, func(repo types.Repository) (string, bool) {
+			if repo.UpdatedAt.IsZero() || !repo.UpdatedAt.Before(since) {
+				return "", false
+			}
+			return "not updated since " + since.Format("2006-01-02"), true
+		})
+	}
+
+	if opts.UpdatedBefore != "" {
+		before, err := parseTime(opts.UpdatedBefore, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid updated-before: %w", err)
+		}
+		f.rules = append(f.rules, func(repo types.Repository) (string, bool) {
+			if repo.UpdatedAt.IsZero() || repo.UpdatedAt.Before(before) {
+				return "", false
+			}
+			return "updated after " + before.Format("2006-01-02"), true
+		})
+	}
+
+	return f, nil
+}
+
+// Excludes reports whether the repository is excluded, with the reason of the first matching rule
+func (f *Filter) Excludes(repo types.Repository) (string, bool) {
+	for _, r := range f.rules {
+		if reason, excluded := r(repo); excluded {
+			return reason, true
+		}
+	}
+	return "", false
+}
+
+// Apply splits the repositories into those to keep and those excluded
+func (f *Filter) Apply(repositories []types.Repository) ([]types.Repository, []Exclusion) {
+	kept := make([]types.Repository, 0, len(repositories))
+	var excluded []Exclusion
+
+	for _, repo := range repositories {
+		if reason, ok := f.Excludes(repo); ok {
+			excluded = append(excluded, Exclusion{Repository: repo, Reason: reason})
+			continue
+		}
+		kept = append(kept, repo)
+	}
+
+	return kept, excluded
+}
+
+// matcher matches repository names against a glob or a regular expression
+type matcher struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// newMatchers compiles the patterns
+func newMatchers(patterns []string) ([]matcher, error) {
+	matchers := make([]matcher, 0, len(patterns))
+	for _, pattern := range patterns {
+		m := matcher{pattern: pattern}
+		if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, err
+			}
+			m.re = re
+		} else if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s: %w", pattern, err)
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+// match reports whether the repository matches the pattern
+func (m matcher) match(repo types.Repository) bool {
+	if m.re != nil {
+		return m.re.MatchString(repo.FullName)
+	}
+
+	name := repo.Name
+	if strings.Contains(m.pattern, "/") {
+		name = repo.FullName
+	}
+	matched, _ := path.Match(m.pattern, name)
+	return matched
+}
+
+// periodPattern matches periods such as 30d, 12w, 6m and 2y
+var periodPattern = regexp.MustCompile(`^(\d+)([dwmy])$`)
+
+// parseTime parses a date (2006-01-02) or a period before now
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if match := periodPattern.FindStringSubmatch(value); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		switch match[2] {
+		case "d":
+			return now.AddDate(0, 0, -n), nil
+		case "w":
+			return now.AddDate(0, 0, -7*n), nil
+		case "m":
+			return now.AddDate(0, -n, 0), nil
+		default:
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a date (2006-01-02) nor a period such as 30d, 12w, 6m or 2y", value)
+	}
+	return t, nil
+}
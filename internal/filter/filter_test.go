@@ -0,0 +1,133 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+func TestApply(t *testing.T) {
+	repositories := []types.Repository{
+		{FullName: "acme/api"},
+		{FullName: "acme/legacy", Archived: true},
+		{FullName: "acme/fork", Fork: true},
+		{FullName: "acme/locked", Disabled: true},
+		{FullName: "acme/new", Empty: true},
+		{FullName: "acme/old-fork", Archived: true, Fork: true},
+	}
+
+	f, err := New(Options{ExcludeArchived: true, ExcludeForks: true, ExcludeDisabled: true, SkipEmpty: true})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	kept, excluded := f.Apply(repositories)
+	if len(kept) != 1 || kept[0].FullName != "acme/api" {
+		t.Errorf("Expected only acme/api to be kept, got %+v", kept)
+	}
+
+	reasons := map[string]string{
+		"acme/legacy":   "archived",
+		"acme/fork":     "fork",
+		"acme/locked":   "disabled",
+		"acme/new":      "empty",
+		"acme/old-fork": "archived",
+	}
+	if len(excluded) != len(reasons) {
+		t.Fatalf("Expected %d exclusions, got %+v", len(reasons), excluded)
+	}
+	for _, e := range excluded {
+		if reasons[e.Repository.FullName] != e.Reason {
+			t.Errorf("Expected %s to be excluded as '%s', got '%s'", e.Repository.FullName, reasons[e.Repository.FullName], e.Reason)
+		}
+	}
+}
+
+func TestApplyWithoutOptions(t *testing.T) {
+	f, _ := New(Options{})
+	kept, excluded := f.Apply([]types.Repository{{FullName: "acme/legacy", Archived: true}})
+	if len(kept) != 1 || len(excluded) != 0 {
+		t.Errorf("Expected no repositories to be excluded, got %+v", excluded)
+	}
+}
+
+func TestApplyPatterns(t *testing.T) {
+	now := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	repositories := []types.Repository{
+		{Name: "api", FullName: "acme/api", Language: "Go", UpdatedAt: now.AddDate(0, -1, 0)},
+		{Name: "ml", FullName: "acme/ml", Language: "Python", UpdatedAt: now.AddDate(-1, 0, 0)},
+		{Name: "api-sandbox", FullName: "acme/api-sandbox", Language: "Go", UpdatedAt: now},
+		{Name: "web", FullName: "acme/web", Language: "TypeScript", UpdatedAt: now},
+		{Name: "legacy", FullName: "acme/legacy", Language: "Go", UpdatedAt: now.AddDate(-3, 0, 0)},
+		{Name: "tools", FullName: "acme/tools", Language: "go"},
+		{Name: "infra", FullName: "acme/infra", UpdatedAt: now},
+	}
+
+	// All Go and Python repositories touched in the last two years except *-sandbox
+	f, err := New(Options{
+		Exclude:      []string{"*-sandbox"},
+		Languages:    []string{"Go", "Python"},
+		UpdatedSince: "2y",
+		Now:          now,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	kept, excluded := f.Apply(repositories)
+	var names []string
+	for _, repo := range kept {
+		names = append(names, repo.FullName)
+	}
+	if strings.Join(names, ",") != "acme/api,acme/ml,acme/tools,acme/infra" {
+		t.Errorf("Unexpected repositories kept: %v", names)
+	}
+
+	reasons := map[string]string{
+		"acme/api-sandbox": "matches exclude pattern *-sandbox",
+		"acme/web":         "language TypeScript",
+		"acme/legacy":      "not updated since 2023-10-01",
+	}
+	for _, e := range excluded {
+		if reasons[e.Repository.FullName] != e.Reason {
+			t.Errorf("Expected %s to be excluded as '%s', got '%s'", e.Repository.FullName, reasons[e.Repository.FullName], e.Reason)
+		}
+	}
+}
+
+func TestApplyIncludeTopicsVisibility(t *testing.T) {
+	repositories := []types.Repository{
+		{Name: "api", FullName: "acme/api", Topics: []string{"backend"}},
+		{Name: "web", FullName: "acme/web", Topics: []string{"Frontend"}, Private: true},
+		{Name: "docs", FullName: "other/docs", Topics: []string{"frontend"}},
+	}
+
+	f, err := New(Options{Include: []string{"acme/*", "re:^other/"}, Topics: []string{"frontend"}, Visibility: "public"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	kept, excluded := f.Apply(repositories)
+	if len(kept) != 1 || kept[0].FullName != "other/docs" {
+		t.Errorf("Expected only other/docs to be kept, got %+v", kept)
+	}
+	if len(excluded) != 2 || excluded[0].Reason != "no matching topic" || excluded[1].Reason != "private" {
+		t.Errorf("Unexpected exclusions %+v", excluded)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	for _, opts := range []Options{
+		{Include: []string{"re:("}},
+		{Exclude: []string{"[a-"}},
+		{Visibility: "internal"},
+		{UpdatedSince: "two years"},
+		{UpdatedBefore: "2024-13-01"},
+	} {
+		if _, err := New(opts); err == nil {
+			t.Errorf("Expected New(%+v) to fail", opts)
+		}
+	}
+}
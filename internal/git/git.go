@@ -8,72 +8,214 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jonasbn/baseline/internal/layout"
+	"github.com/jonasbn/baseline/internal/remote"
 	"github.com/jonasbn/baseline/internal/types"
 )
 
+const (
+	// ArchiveDir is the directory below the baseline holding archived repositories
+	ArchiveDir = "_archive"
+	// MetadataDir is the directory below the baseline holding data kept by baseline
+	MetadataDir = ".baseline"
+)
+
 // GitOps provides Git operations for baseline
 type GitOps struct {
 	verbose bool
+	layout  *layout.Layout
+	mode    Mode
+
+	strategy      Strategy
+	strategyRules []StrategyRule
+
+	sparse           Sparse
+	sparseRules      []SparseRule
+	sparseConfigured bool
+
+	submodules bool
+	lfs        LFSPolicy
+	lfsMissing bool
+}
+
+// Options configures GitOps
+type Options struct {
+	// Layout resolves the directory of each repository, defaults to owner/name
+	Layout *layout.Layout
+	// Mode selects checkouts or mirrors, defaults to checkout
+	Mode Mode
+	// ConvertMode allows replacing existing repositories stored in the other mode
+	ConvertMode bool
+	// Strategy selects shallow or partial clones for all repositories
+	Strategy Strategy
+	// StrategyRules override Strategy for the repositories they match, first match wins
+	StrategyRules []StrategyRule
+	// Sparse limits the working tree of checkouts to the given patterns
+	Sparse Sparse
+	// SparseRules override Sparse for the repositories they match, first match wins.
+	// Sparse patterns of existing checkouts are only changed when Sparse or
+	// SparseRules are given.
+	SparseRules []SparseRule
+	// Submodules initializes and updates submodules recursively in checkouts
+	Submodules bool
+	// LFS selects how Git LFS content is handled in checkouts
+	LFS LFSPolicy
+	// LFSMissing is set when git-lfs is not installed, checkouts of repositories using
+	// LFS then report an LFS error with the default policy
+	LFSMissing bool
 }
 
 // NewGitOps creates a new GitOps instance
 func NewGitOps(verbose bool) *GitOps {
+	return NewGitOpsWithOptions(verbose, Options{})
+}
+
+// NewGitOpsWithOptions creates a new GitOps instance with the given options
+func NewGitOpsWithOptions(verbose bool, opts Options) *GitOps {
+	if opts.Layout == nil {
+		opts.Layout = layout.Default()
+	}
+	if opts.Mode == "" {
+		opts.Mode = ModeCheckout
+	}
+
 	return &GitOps{
 		verbose: verbose,
+		layout:  opts.Layout,
+		mode:    opts.Mode,
+
+		strategy:      opts.Strategy,
+		strategyRules: opts.StrategyRules,
+
+		sparse:           opts.Sparse,
+		sparseRules:      opts.SparseRules,
+		sparseConfigured: !opts.Sparse.IsEmpty() || len(opts.SparseRules) > 0,
+
+		submodules: opts.Submodules,
+		lfs:        opts.LFS,
+		lfsMissing: opts.LFSMissing,
 	}
 }
 
-// CloneRepository clones a repository as bare to the specified directory
+// RepositoryPath returns the directory of the repository in the target directory
+func (g *GitOps) RepositoryPath(repo types.Repository, targetDir string) string {
+	return g.layout.Path(repo, targetDir)
+}
+
+// CloneRepository clones a repository to the specified directory, as a checkout
+// or as a mirror depending on the mode
 func (g *GitOps) CloneRepository(repo types.Repository, targetDir string) types.CloneResult {
 	start := time.Now()
-	result := types.CloneResult{
-		Repository: repo,
-		Success:    false,
-		Duration:   0,
-	}
 
 	// Create the repository directory path
-	repoPath := filepath.Join(targetDir, repo.Owner, repo.Name)
+	repoPath := g.RepositoryPath(repo, targetDir)
 
 	// Check if repository already exists
 	if _, err := os.Stat(repoPath); err == nil {
-		result.Error = fmt.Errorf("repository already exists at %s", repoPath)
-		result.Duration = time.Since(start)
-		return result
+		return types.CloneResult{
+			Repository: repo,
+			Error:      fmt.Errorf("repository already exists at %s", repoPath),
+			Duration:   time.Since(start),
+		}
+	}
+
+	result := g.cloneTo(repo, repoPath)
+	result.Duration = time.Since(start)
+	return result
+}
+
+// cloneTo clones repo into repoPath and makes the clone read-only
+func (g *GitOps) cloneTo(repo types.Repository, repoPath string) types.CloneResult {
+	result := types.CloneResult{
+		Repository: repo,
+		Success:    false,
 	}
 
 	// Create parent directory if it doesn't exist
 	if err := os.MkdirAll(filepath.Dir(repoPath), 0755); err != nil {
 		result.Error = fmt.Errorf("failed to create parent directory: %w", err)
-		result.Duration = time.Since(start)
 		return result
 	}
 
-	// Clone the repository
-	cmd := exec.Command("git", "clone", repo.CloneURL, repoPath)
+	// Clone the repository, checking out a specific branch if requested.
+	// A mirror holds all branches, so there is nothing to check out.
+	args := []string{"clone"}
+	sparse, _ := g.sparseFor(repo)
+	if g.mode == ModeMirror {
+		args = append(args, "--mirror")
+	} else {
+		if repo.Branch != "" {
+			args = append(args, "--branch", repo.Branch)
+		}
+		// Check out only the files in the root directory until the patterns are set
+		if !sparse.IsEmpty() {
+			args = append(args, "--sparse")
+		}
+	}
+	strategy := g.strategyFor(repo)
+	args = append(args, strategy.cloneArgs()...)
+	if repo.LocalPath != "" {
+		if repo.CloneURL == repo.LocalPath {
+			// Copy objects instead of hardlinking them, changing permissions in
+			// the baseline must not affect the existing clone
+			args = append(args, "--no-hardlinks")
+		} else {
+			// Borrow objects from the existing clone and only download what is missing
+			args = append(args, "--reference-if-able", repo.LocalPath, "--dissociate")
+		}
+	}
+	args = append(args, repo.CloneURL, repoPath)
+	cmd := g.command(args...)
 	if g.verbose {
 		fmt.Printf("Cloning %s to %s\n", repo.FullName, repoPath)
 	}
 
 	if err := cmd.Run(); err != nil {
 		result.Error = fmt.Errorf("failed to clone repository %s: %w", repo.FullName, err)
-		result.Duration = time.Since(start)
 		return result
 	}
 
+	if err := g.recordStrategy(repoPath, strategy); err != nil {
+		result.Error = fmt.Errorf("failed to record clone strategy for %s: %w", repoPath, err)
+		return result
+	}
+
+	if err := g.recordIdentity(repoPath, repo); err != nil {
+		result.Error = fmt.Errorf("failed to record repository ID for %s: %w", repoPath, err)
+		return result
+	}
+
+	if g.mode == ModeCheckout && !sparse.IsEmpty() {
+		if _, err := g.applySparse(repo, repoPath); err != nil {
+			result.Error = fmt.Errorf("failed to set sparse checkout for %s: %w", repoPath, err)
+			return result
+		}
+	}
+
+	// Failing submodules do not fail the repository itself
+	if g.mode == ModeCheckout && g.submodules {
+		result.SubmoduleError = g.updateSubmodules(repoPath)
+	}
+
+	// Failing LFS downloads do not fail the repository either
+	if g.mode == ModeCheckout {
+		result.LFSError = g.pullLFS(repoPath)
+	}
+
 	// Set permissions to read-only
 	if err := g.setReadOnlyPermissions(repoPath); err != nil {
 		result.Error = fmt.Errorf("failed to set read-only permissions for %s: %w", repoPath, err)
-		result.Duration = time.Since(start)
 		return result
 	}
 
 	result.Success = true
-	result.Duration = time.Since(start)
 	return result
 }
 
-// UpdateRepository updates an existing bare repository
+// UpdateRepository fetches an existing repository and fast-forwards the checked-out
+// branch to its upstream. Local commits are never discarded: a branch that is ahead
+// of or has diverged from its upstream is left untouched and reported in the result.
+// A mirror has all of its refs updated instead.
 func (g *GitOps) UpdateRepository(repo types.Repository, targetDir string) types.UpdateResult {
 	start := time.Now()
 	result := types.UpdateResult{
@@ -83,7 +225,7 @@ func (g *GitOps) UpdateRepository(repo types.Repository, targetDir string) types
 		Duration:   0,
 	}
 
-	repoPath := filepath.Join(targetDir, repo.Owner, repo.Name)
+	repoPath := g.RepositoryPath(repo, targetDir)
 
 	// Check if repository exists
 	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
@@ -92,100 +234,392 @@ func (g *GitOps) UpdateRepository(repo types.Repository, targetDir string) types
 		return result
 	}
 
-	// Temporarily set write permissions
+	// Temporarily set write permissions, fast-forwarding replaces files in the working tree
 	if err := g.setWritePermissions(repoPath); err != nil {
+		g.setReadOnlyPermissions(repoPath)
 		result.Error = fmt.Errorf("failed to set write permissions for %s: %w", repoPath, err)
 		result.Duration = time.Since(start)
 		return result
 	}
 
-	// Get current HEAD before update
-	oldHead, err := g.getCurrentHead(repoPath)
-	if err != nil {
-		result.Error = fmt.Errorf("failed to get current HEAD for %s: %w", repoPath, err)
-		result.Duration = time.Since(start)
-		return result
-	}
-
-	// Fetch updates
-	cmd := exec.Command("git", "-C", repoPath, "fetch", "origin")
 	if g.verbose {
 		fmt.Printf("Updating %s at %s\n", repo.FullName, repoPath)
 	}
 
-	if err := cmd.Run(); err != nil {
-		// Restore read-only permissions even if update fails
-		g.setReadOnlyPermissions(repoPath)
+	var state types.UpdateState
+	var oldHead, newHead string
+	var err error
+	if g.mode == ModeMirror {
+		oldHead, newHead, err = g.updateMirror(repoPath)
+	} else {
+		state, oldHead, newHead, err = g.updateWorkingTree(repoPath)
+	}
+	if err != nil {
 		result.Error = fmt.Errorf("failed to update repository %s: %w", repo.FullName, err)
-		result.Duration = time.Since(start)
-		return result
 	}
 
-	// Get new HEAD after update
-	newHead, err := g.getCurrentHead(repoPath)
-	if err != nil {
-		result.Error = fmt.Errorf("failed to get new HEAD for %s: %w", repoPath, err)
-		result.Duration = time.Since(start)
-		return result
+	// Record the ID of repositories cloned before IDs were recorded
+	if result.Error == nil {
+		if err := g.recordIdentity(repoPath, repo); err != nil {
+			result.Error = fmt.Errorf("failed to record repository ID for %s: %w", repoPath, err)
+		}
 	}
 
-	// Check if repository was actually updated
-	result.Updated = oldHead != newHead
+	// Re-apply the sparse patterns if they changed since the last run
+	var sparseChanged bool
+	if result.Error == nil && g.mode == ModeCheckout && g.sparseConfigured {
+		if sparseChanged, err = g.applySparse(repo, repoPath); err != nil {
+			result.Error = fmt.Errorf("failed to update sparse checkout of %s: %w", repo.FullName, err)
+		}
+	}
 
-	// Restore read-only permissions
-	if err := g.setReadOnlyPermissions(repoPath); err != nil {
+	// Failing submodules do not fail the repository itself
+	if result.Error == nil && g.mode == ModeCheckout && g.submodules {
+		result.SubmoduleError = g.updateSubmodules(repoPath)
+	}
+
+	// Failing LFS downloads do not fail the repository either
+	if result.Error == nil && g.mode == ModeCheckout {
+		result.LFSError = g.pullLFS(repoPath)
+	}
+
+	// Restore read-only permissions, also if the update failed. This includes
+	// the submodules, which are checked out inside the working tree.
+	if err := g.setReadOnlyPermissions(repoPath); err != nil && result.Error == nil {
 		result.Error = fmt.Errorf("failed to restore read-only permissions for %s: %w", repoPath, err)
+	}
+
+	if result.Error != nil {
 		result.Duration = time.Since(start)
 		return result
 	}
 
+	// The repository was updated if the checked-out commit, or any ref of a mirror, actually
+	// moved, or if the sparse patterns changed the working tree
+	result.State = state
+	result.Updated = oldHead != newHead || sparseChanged
 	result.Success = true
 	result.Duration = time.Since(start)
 	return result
 }
 
+// updateWorkingTree fetches origin and fast-forwards the checked-out branch to its
+// upstream, returning the resulting state and the HEAD commit before and after
+func (g *GitOps) updateWorkingTree(repoPath string) (types.UpdateState, string, string, error) {
+	// Get current HEAD before update
+	oldHead, err := g.getCurrentHead(repoPath)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to get current HEAD: %w", err)
+	}
+
+	// The upstream before fetching tells whether the branch had local commits,
+	// which cannot be determined from the history of a shallow repository
+	oldUpstream, _ := g.git(repoPath, "rev-parse", "--verify", "-q", "@{upstream}")
+
+	// Fetch updates, keeping shallow repositories shallow. Submodules are fetched
+	// separately, so their failures do not fail the repository.
+	shallowArgs := g.shallowFetchArgs(repoPath)
+	fetchArgs := append(append([]string{"fetch", "--recurse-submodules=no"}, shallowArgs...), "origin")
+	if _, err := g.git(repoPath, fetchArgs...); err != nil {
+		return "", oldHead, oldHead, err
+	}
+
+	// A detached HEAD or a branch without upstream cannot be fast-forwarded
+	if _, err := g.git(repoPath, "symbolic-ref", "-q", "HEAD"); err != nil {
+		return types.UpdateStateDetached, oldHead, oldHead, nil
+	}
+
+	upstream, err := g.git(repoPath, "rev-parse", "--verify", "-q", "@{upstream}")
+	if err != nil {
+		return types.UpdateStateNoUpstream, oldHead, oldHead, nil
+	}
+
+	if upstream == oldHead {
+		return types.UpdateStateUpToDate, oldHead, oldHead, nil
+	}
+
+	// The history connecting the old and the new upstream commit is cut off in a
+	// shallow fetch. A branch that matched its upstream before has no local commits,
+	// so it is moved to the new upstream, keeping local changes to the working tree.
+	if len(shallowArgs) > 0 && oldHead == oldUpstream {
+		// Restoring write permissions changes the change time of every file. Unlike
+		// merge, reset --keep does not refresh the index and refuses to move files
+		// whose stat information is outdated, so refresh it first; real local
+		// modifications are still detected by reset --keep.
+		g.git(repoPath, "update-index", "-q", "--refresh")
+		if _, err := g.git(repoPath, "reset", "--keep", "--quiet", "@{upstream}"); err != nil {
+			return "", oldHead, oldHead, fmt.Errorf("failed to move to upstream: %w", err)
+		}
+		return g.movedTo(repoPath, oldHead)
+	}
+
+	// Only fast-forward if the checked-out commit is contained in the upstream
+	if _, err := g.git(repoPath, "merge-base", "--is-ancestor", "HEAD", "@{upstream}"); err != nil {
+		if _, err := g.git(repoPath, "merge-base", "--is-ancestor", "@{upstream}", "HEAD"); err == nil {
+			return types.UpdateStateAhead, oldHead, oldHead, nil
+		}
+		return types.UpdateStateDiverged, oldHead, oldHead, nil
+	}
+
+	if _, err := g.git(repoPath, "merge", "--ff-only", "--quiet", "@{upstream}"); err != nil {
+		return "", oldHead, oldHead, fmt.Errorf("failed to fast-forward: %w", err)
+	}
+
+	return g.movedTo(repoPath, oldHead)
+}
+
+// movedTo returns the fast-forwarded state with the HEAD commit before and after
+func (g *GitOps) movedTo(repoPath, oldHead string) (types.UpdateState, string, string, error) {
+	// Get new HEAD after update
+	newHead, err := g.getCurrentHead(repoPath)
+	if err != nil {
+		return "", oldHead, oldHead, fmt.Errorf("failed to get new HEAD: %w", err)
+	}
+
+	return types.UpdateStateFastForwarded, oldHead, newHead, nil
+}
+
+// updateSubmodules initializes and updates the submodules of the checkout recursively,
+// following URL changes in .gitmodules
+func (g *GitOps) updateSubmodules(repoPath string) error {
+	if _, err := g.git(repoPath, "submodule", "sync", "--recursive"); err != nil {
+		return fmt.Errorf("failed to sync submodules: %w", err)
+	}
+	if _, err := g.git(repoPath, "submodule", "update", "--init", "--recursive"); err != nil {
+		return fmt.Errorf("failed to update submodules: %w", err)
+	}
+	return nil
+}
+
+// updateMirror updates all refs of a mirror, removing branches and tags deleted
+// upstream, and returns the refs before and after
+func (g *GitOps) updateMirror(repoPath string) (string, string, error) {
+	oldRefs, err := g.listRefs(repoPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to list refs: %w", err)
+	}
+
+	// Shallow mirrors are fetched with the recorded depth instead of being deepened
+	if shallowArgs := g.shallowFetchArgs(repoPath); len(shallowArgs) > 0 {
+		args := append(append([]string{"fetch", "--prune"}, shallowArgs...), "origin")
+		if _, err := g.git(repoPath, args...); err != nil {
+			return "", "", err
+		}
+	} else if _, err := g.git(repoPath, "remote", "update", "--prune"); err != nil {
+		return "", "", err
+	}
+
+	newRefs, err := g.listRefs(repoPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to list refs: %w", err)
+	}
+
+	return oldRefs, newRefs, nil
+}
+
+// git runs a Git command in the repository at repoPath and returns its trimmed output.
+// The error includes the message Git printed on standard error.
+func (g *GitOps) git(repoPath string, args ...string) (string, error) {
+	cmd := g.command(append([]string{"-C", repoPath}, args...)...)
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, message)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
 // RepositoryExists checks if a repository already exists in the target directory
 func (g *GitOps) RepositoryExists(repo types.Repository, targetDir string) bool {
-	repoPath := filepath.Join(targetDir, repo.Owner, repo.Name)
+	repoPath := g.RepositoryPath(repo, targetDir)
 	_, err := os.Stat(repoPath)
 	return err == nil
 }
 
-// setReadOnlyPermissions sets read-only permissions recursively
+// CheckOrigin verifies that the existing repository at repoPath is a clone of repo,
+// so that a different repository mapped to the same directory is not mistaken for it
+func (g *GitOps) CheckOrigin(repo types.Repository, repoPath string) error {
+	originURL, err := RemoteURL(repoPath, "origin")
+	if err != nil {
+		// Without an origin remote there is nothing to compare against
+		return nil
+	}
+
+	origin, err := remote.Parse(originURL)
+	if err != nil {
+		return nil
+	}
+
+	for _, u := range []string{repo.CloneURL, repo.SSHURL, repo.HTTPSURL, repo.LocalPath} {
+		if u == "" {
+			continue
+		}
+		if u == originURL {
+			return nil
+		}
+
+		r, err := remote.Parse(u)
+		if err == nil && strings.EqualFold(r.Host, origin.Host) && strings.EqualFold(r.Path, origin.Path) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("directory %s already holds a clone of %s, use a layout such as --layout host to avoid the collision", repoPath, originURL)
+}
+
+// MoveRepository moves the repository at oldPath to newPath, temporarily making the
+// read-only repository directory writable, as moving a directory updates its ".." entry.
+// Parent directories left empty below stopDir are removed.
+func (g *GitOps) MoveRepository(oldPath, newPath, stopDir string) error {
+	if _, err := os.Stat(newPath); err == nil {
+		return fmt.Errorf("cannot move %s: %s already exists", oldPath, newPath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory: %w", err)
+	}
+
+	info, err := os.Stat(oldPath)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(oldPath, 0755); err != nil {
+		return fmt.Errorf("failed to set write permissions for %s: %w", oldPath, err)
+	}
+
+	if err := os.Rename(oldPath, newPath); err != nil {
+		os.Chmod(oldPath, info.Mode().Perm())
+		return fmt.Errorf("failed to move %s to %s: %w", oldPath, newPath, err)
+	}
+
+	if err := os.Chmod(newPath, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to restore permissions for %s: %w", newPath, err)
+	}
+
+	if g.verbose {
+		fmt.Printf("Moved %s to %s\n", oldPath, newPath)
+	}
+
+	removeEmptyParents(filepath.Dir(oldPath), stopDir)
+	return nil
+}
+
+// DeleteRepository removes the read-only repository at repoPath. Parent directories
+// left empty below stopDir are removed.
+func (g *GitOps) DeleteRepository(repoPath, stopDir string) error {
+	if err := g.setWritePermissions(repoPath); err != nil {
+		return fmt.Errorf("failed to set write permissions for %s: %w", repoPath, err)
+	}
+
+	if err := os.RemoveAll(repoPath); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", repoPath, err)
+	}
+
+	if g.verbose {
+		fmt.Printf("Removed %s\n", repoPath)
+	}
+
+	removeEmptyParents(filepath.Dir(repoPath), stopDir)
+	return nil
+}
+
+// removeEmptyParents removes dir and its parents while they are empty, stopping at stopDir
+func removeEmptyParents(dir, stopDir string) {
+	stopDir = filepath.Clean(stopDir)
+	for dir = filepath.Clean(dir); dir != stopDir && strings.HasPrefix(dir, stopDir+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+	}
+}
+
+// FindRepositories walks root and returns the paths of all Git repositories below it,
+// checkouts as well as mirrors. Repositories nested inside other repositories, and the
+// archive and metadata directories of the baseline are not returned.
+func FindRepositories(root string) ([]string, error) {
+	var repos []string
+
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			// Skip directories we are not allowed to read
+			if d != nil && d.IsDir() && os.IsPermission(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		if filepath.Dir(path) == filepath.Clean(root) && (d.Name() == ArchiveDir || d.Name() == MetadataDir) {
+			return filepath.SkipDir
+		}
+
+		// A .git directory or file (worktrees, submodules) marks a repository
+		if _, err := os.Lstat(filepath.Join(path, ".git")); err == nil {
+			repos = append(repos, path)
+			return filepath.SkipDir
+		}
+
+		// Mirrors are bare repositories without a .git directory
+		if path != root && isBareRepository(path) {
+			repos = append(repos, path)
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+
+	return repos, err
+}
+
+// RemoteURL returns the URL configured for the named remote of the repository at repoPath
+func RemoteURL(repoPath, remote string) (string, error) {
+	cmd := exec.Command("git", "-C", repoPath, "remote", "get-url", remote)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get URL of remote %s for %s: %w", remote, repoPath, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// setReadOnlyPermissions removes the write permissions recursively. The other
+// permission bits are kept, Git would see a mode change if the execute bit of a
+// tracked file was dropped.
 func (g *GitOps) setReadOnlyPermissions(path string) error {
 	return filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-
-		// Set read-only permissions (owner: read+execute, group: read, others: read)
-		var mode os.FileMode
-		if info.IsDir() {
-			mode = 0555 // read + execute for directories
-		} else {
-			mode = 0444 // read-only for files
+		// The mode of a symbolic link is that of its target, which is walked on its own
+		if info.Mode()&os.ModeSymlink != 0 {
+			return nil
 		}
 
-		return os.Chmod(filePath, mode)
+		return os.Chmod(filePath, info.Mode().Perm()&^0222)
 	})
 }
 
-// setWritePermissions sets write permissions recursively
+// setWritePermissions restores the write permission of the owner recursively
 func (g *GitOps) setWritePermissions(path string) error {
 	return filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-
-		// Set write permissions (owner: read+write+execute, group: read, others: read)
-		var mode os.FileMode
-		if info.IsDir() {
-			mode = 0755 // read + write + execute for directories
-		} else {
-			mode = 0644 // read + write for files
+		if info.Mode()&os.ModeSymlink != 0 {
+			return nil
 		}
 
-		return os.Chmod(filePath, mode)
+		return os.Chmod(filePath, info.Mode().Perm()|0200)
 	})
 }
 
@@ -4,7 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/jonasbn/baseline/internal/testutil"
 	"github.com/jonasbn/baseline/internal/types"
 )
 
@@ -81,3 +83,377 @@ func TestSetReadOnlyPermissions(t *testing.T) {
 	// Cleanup: restore write permissions so temp dir can be cleaned up
 	defer gitOps.setWritePermissions(tempDir)
 }
+
+func TestFindRepositories(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Two repositories, one nested inside the other, and a plain directory
+	for _, dir := range []string{"acme/api/.git", "acme/api/vendor/lib/.git", "jdoe/tools/.git", "notes"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+	}
+
+	repos, err := FindRepositories(tempDir)
+	if err != nil {
+		t.Fatalf("FindRepositories failed: %v", err)
+	}
+
+	expected := []string{filepath.Join(tempDir, "acme/api"), filepath.Join(tempDir, "jdoe/tools")}
+	if len(repos) != len(expected) {
+		t.Fatalf("Expected %d repositories, got %v", len(expected), repos)
+	}
+
+	for i := range expected {
+		if repos[i] != expected[i] {
+			t.Errorf("Expected repository '%s', got '%s'", expected[i], repos[i])
+		}
+	}
+}
+
+func TestMoveRepository(t *testing.T) {
+	gitOps := NewGitOps(false)
+	tempDir := t.TempDir()
+
+	oldPath := filepath.Join(tempDir, "acme", "api")
+	if err := os.MkdirAll(filepath.Join(oldPath, ".git"), 0755); err != nil {
+		t.Fatalf("Failed to create test repository directory: %v", err)
+	}
+	if err := gitOps.setReadOnlyPermissions(oldPath); err != nil {
+		t.Fatalf("Failed to set read-only permissions: %v", err)
+	}
+
+	newPath := filepath.Join(tempDir, "github.com", "acme", "api")
+	if err := gitOps.MoveRepository(oldPath, newPath, tempDir); err != nil {
+		t.Fatalf("MoveRepository failed: %v", err)
+	}
+	defer gitOps.setWritePermissions(newPath)
+
+	if _, err := os.Stat(filepath.Join(newPath, ".git")); err != nil {
+		t.Errorf("Repository should exist at the new path: %v", err)
+	}
+
+	// The emptied owner directory is removed, the baseline directory is kept
+	if _, err := os.Stat(filepath.Join(tempDir, "acme")); !os.IsNotExist(err) {
+		t.Error("Empty owner directory should have been removed")
+	}
+
+	info, err := os.Stat(newPath)
+	if err != nil {
+		t.Fatalf("Failed to get directory info: %v", err)
+	}
+	if info.Mode().Perm()&0200 != 0 {
+		t.Error("Moved repository should still be read-only")
+	}
+}
+
+// setupUpstream creates a bare upstream repository with one commit and a working
+// clone used to push further commits to it
+func setupUpstream(t *testing.T, tempDir string) (string, string) {
+	t.Helper()
+	testutil.SetIdentity(t)
+
+	upstream := filepath.Join(tempDir, "upstream.git")
+	work := filepath.Join(tempDir, "work")
+	testutil.Git(t, tempDir, "init", "--bare", "-b", "main", upstream)
+	testutil.Git(t, tempDir, "clone", upstream, work)
+	testutil.CommitFiles(t, work, map[string]string{"README.md": "one"})
+	testutil.Git(t, work, "push", "origin", "HEAD:main")
+
+	return upstream, work
+}
+
+func TestUpdateRepositoryFastForward(t *testing.T) {
+	gitOps := NewGitOps(false)
+	tempDir := t.TempDir()
+	upstream, work := setupUpstream(t, tempDir)
+
+	baseline := filepath.Join(tempDir, "baseline")
+	repo := types.Repository{Name: "api", Owner: "acme", FullName: "acme/api", CloneURL: upstream}
+	if result := gitOps.CloneRepository(repo, baseline); !result.Success {
+		t.Fatalf("CloneRepository failed: %v", result.Error)
+	}
+	repoPath := gitOps.RepositoryPath(repo, baseline)
+	defer gitOps.setWritePermissions(repoPath)
+
+	result := gitOps.UpdateRepository(repo, baseline)
+	if result.Error != nil || result.Updated || result.State != types.UpdateStateUpToDate {
+		t.Errorf("Expected repository to be up to date, got %+v", result)
+	}
+
+	testutil.CommitFiles(t, work, map[string]string{"README.md": "two"})
+	testutil.Git(t, work, "push", "-q", "origin", "HEAD:main")
+
+	result = gitOps.UpdateRepository(repo, baseline)
+	if result.Error != nil || !result.Updated || result.State != types.UpdateStateFastForwarded {
+		t.Fatalf("Expected repository to be fast-forwarded, got %+v", result)
+	}
+
+	content, err := os.ReadFile(filepath.Join(repoPath, "README.md"))
+	if err != nil || string(content) != "two" {
+		t.Errorf("Expected working tree to contain the new commit, got %q (%v)", content, err)
+	}
+}
+
+func TestUpdateRepositoryExecutable(t *testing.T) {
+	gitOps := NewGitOps(false)
+	tempDir := t.TempDir()
+	upstream, work := setupUpstream(t, tempDir)
+
+	script := filepath.Join(work, "run.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	testutil.Git(t, work, "add", "run.sh")
+	testutil.Git(t, work, "commit", "-q", "-m", "Add run.sh")
+	testutil.Git(t, work, "push", "-q", "origin", "HEAD:main")
+
+	baseline := filepath.Join(tempDir, "baseline")
+	repo := types.Repository{Name: "api", Owner: "acme", FullName: "acme/api", CloneURL: upstream}
+	if result := gitOps.CloneRepository(repo, baseline); !result.Success {
+		t.Fatalf("CloneRepository failed: %v", result.Error)
+	}
+	repoPath := gitOps.RepositoryPath(repo, baseline)
+	defer gitOps.setWritePermissions(repoPath)
+
+	// Making the checkout read-only keeps the execute bit, so Git sees no local change
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho two\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	testutil.Git(t, work, "commit", "-q", "-a", "-m", "Change run.sh")
+	testutil.Git(t, work, "push", "-q", "origin", "HEAD:main")
+
+	result := gitOps.UpdateRepository(repo, baseline)
+	if result.Error != nil || result.State != types.UpdateStateFastForwarded {
+		t.Fatalf("Expected repository to be fast-forwarded, got %+v", result)
+	}
+
+	info, err := os.Stat(filepath.Join(repoPath, "run.sh"))
+	if err != nil {
+		t.Fatalf("Failed to get file info: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode&0100 == 0 || mode&0222 != 0 {
+		t.Errorf("Expected run.sh to stay executable and read-only, got %v", mode)
+	}
+}
+
+func TestUpdateRepositoryDiverged(t *testing.T) {
+	gitOps := NewGitOps(false)
+	tempDir := t.TempDir()
+	upstream, work := setupUpstream(t, tempDir)
+
+	baseline := filepath.Join(tempDir, "baseline")
+	repo := types.Repository{Name: "api", Owner: "acme", FullName: "acme/api", CloneURL: upstream}
+	if result := gitOps.CloneRepository(repo, baseline); !result.Success {
+		t.Fatalf("CloneRepository failed: %v", result.Error)
+	}
+	repoPath := gitOps.RepositoryPath(repo, baseline)
+	defer gitOps.setWritePermissions(repoPath)
+
+	// A local commit makes the branch ahead of its upstream
+	gitOps.setWritePermissions(repoPath)
+	testutil.CommitFiles(t, repoPath, map[string]string{"LOCAL.md": "local"})
+	localHead, _ := gitOps.getCurrentHead(repoPath)
+
+	result := gitOps.UpdateRepository(repo, baseline)
+	if result.Error != nil || result.State != types.UpdateStateAhead {
+		t.Errorf("Expected repository to be ahead, got %+v", result)
+	}
+
+	testutil.CommitFiles(t, work, map[string]string{"README.md": "two"})
+	testutil.Git(t, work, "push", "-q", "origin", "HEAD:main")
+
+	result = gitOps.UpdateRepository(repo, baseline)
+	if result.Error != nil || result.Updated || result.State != types.UpdateStateDiverged {
+		t.Errorf("Expected repository to have diverged, got %+v", result)
+	}
+
+	if head, _ := gitOps.getCurrentHead(repoPath); head != localHead {
+		t.Error("Local commit should not have been discarded")
+	}
+}
+
+func TestMirrorMode(t *testing.T) {
+	mirror := NewGitOpsWithOptions(false, Options{Mode: ModeMirror})
+	tempDir := t.TempDir()
+	upstream, work := setupUpstream(t, tempDir)
+
+	baseline := filepath.Join(tempDir, "baseline")
+	repo := types.Repository{Name: "api", Owner: "acme", FullName: "acme/api", CloneURL: upstream}
+	if result := mirror.CloneRepository(repo, baseline); !result.Success {
+		t.Fatalf("CloneRepository failed: %v", result.Error)
+	}
+	repoPath := mirror.RepositoryPath(repo, baseline)
+	defer mirror.setWritePermissions(repoPath)
+
+	if mode, err := DetectMode(repoPath); err != nil || mode != ModeMirror {
+		t.Errorf("Expected mirror mode, got '%s' (%v)", mode, err)
+	}
+
+	// A new branch upstream is an update of the mirror
+	testutil.Git(t, work, "push", "-q", "origin", "HEAD:feature")
+	result := mirror.UpdateRepository(repo, baseline)
+	if result.Error != nil || !result.Updated {
+		t.Fatalf("Expected mirror to be updated, got %+v", result)
+	}
+
+	result = mirror.UpdateRepository(repo, baseline)
+	if result.Error != nil || result.Updated {
+		t.Errorf("Expected mirror to be up to date, got %+v", result)
+	}
+
+	// Modes are never mixed within one repository directory
+	if err := NewGitOps(false).CheckMode(repoPath); err == nil {
+		t.Error("Expected CheckMode to refuse a mirror in checkout mode")
+	}
+
+	repos, err := FindRepositories(baseline)
+	if err != nil || len(repos) != 1 || repos[0] != repoPath {
+		t.Errorf("Expected to find the mirror, got %v (%v)", repos, err)
+	}
+}
+
+func TestConvertRepository(t *testing.T) {
+	gitOps := NewGitOps(false)
+	tempDir := t.TempDir()
+	upstream, _ := setupUpstream(t, tempDir)
+
+	baseline := filepath.Join(tempDir, "baseline")
+	repo := types.Repository{Name: "api", Owner: "acme", FullName: "acme/api", CloneURL: upstream}
+	if result := gitOps.CloneRepository(repo, baseline); !result.Success {
+		t.Fatalf("CloneRepository failed: %v", result.Error)
+	}
+	repoPath := gitOps.RepositoryPath(repo, baseline)
+	defer gitOps.setWritePermissions(repoPath)
+
+	mirror := NewGitOpsWithOptions(false, Options{Mode: ModeMirror, ConvertMode: true})
+	if result := mirror.ConvertRepository(repo, baseline); !result.Success {
+		t.Fatalf("ConvertRepository failed: %v", result.Error)
+	}
+
+	if mode, err := DetectMode(repoPath); err != nil || mode != ModeMirror {
+		t.Errorf("Expected converted repository to be a mirror, got '%s' (%v)", mode, err)
+	}
+
+	for _, leftover := range []string{repoPath + ".baseline-convert", repoPath + ".baseline-old"} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Errorf("Temporary conversion directory %s should have been removed", leftover)
+		}
+	}
+
+	// A conversion interrupted after moving the old repository aside restores it first
+	if err := gitOps.MoveRepository(repoPath, repoPath+".baseline-old", baseline); err != nil {
+		t.Fatalf("MoveRepository failed: %v", err)
+	}
+	if result := gitOps.ConvertRepository(repo, baseline); !result.Success {
+		t.Fatalf("ConvertRepository failed: %v", result.Error)
+	}
+	if mode, err := DetectMode(repoPath); err != nil || mode != ModeCheckout {
+		t.Errorf("Expected converted repository to be a checkout, got '%s' (%v)", mode, err)
+	}
+	if _, err := os.Stat(repoPath + ".baseline-old"); !os.IsNotExist(err) {
+		t.Error("The old repository should have been removed")
+	}
+}
+
+func TestUpdateRepositoryShallow(t *testing.T) {
+	gitOps := NewGitOpsWithOptions(false, Options{Strategy: Strategy{Depth: 1}})
+	tempDir := t.TempDir()
+	upstream, work := setupUpstream(t, tempDir)
+	testutil.CommitFiles(t, work, map[string]string{"README.md": "two"})
+	testutil.Git(t, work, "push", "-q", "origin", "HEAD:main")
+
+	// Shallow clones of a local repository need a file:// URL
+	baseline := filepath.Join(tempDir, "baseline")
+	repo := types.Repository{Name: "api", Owner: "acme", FullName: "acme/api", CloneURL: "file://" + upstream}
+	if result := gitOps.CloneRepository(repo, baseline); !result.Success {
+		t.Fatalf("CloneRepository failed: %v", result.Error)
+	}
+	repoPath := gitOps.RepositoryPath(repo, baseline)
+	defer gitOps.setWritePermissions(repoPath)
+
+	testutil.CommitFiles(t, work, map[string]string{"README.md": "three"})
+	testutil.Git(t, work, "push", "-q", "origin", "HEAD:main")
+
+	// Leave the stat information in the index outdated, as the permission changes of
+	// every update do with the change time of the files, Git only compares seconds
+	readme := filepath.Join(repoPath, "README.md")
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(readme, past, past); err != nil {
+		t.Fatal(err)
+	}
+	gitOps.setWritePermissions(repoPath)
+	testutil.Git(t, repoPath, "update-index", "-q", "--refresh")
+	gitOps.setReadOnlyPermissions(repoPath)
+	if err := os.Chtimes(readme, past.Add(time.Minute), past.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Updates of shallow repositories are independent of the strategy of the run
+	result := NewGitOps(false).UpdateRepository(repo, baseline)
+	if result.Error != nil || !result.Updated || result.State != types.UpdateStateFastForwarded {
+		t.Fatalf("Expected shallow repository to be updated, got %+v", result)
+	}
+
+	if count, err := gitOps.git(repoPath, "rev-list", "--count", "HEAD"); err != nil || count != "1" {
+		t.Errorf("Expected repository to stay at depth 1, got %s commits (%v)", count, err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(repoPath, "README.md"))
+	if err != nil || string(content) != "three" {
+		t.Errorf("Expected working tree to contain the new commit, got %q (%v)", content, err)
+	}
+}
+
+func TestSubmodules(t *testing.T) {
+	tempDir := t.TempDir()
+	upstream, work := setupUpstream(t, tempDir)
+
+	// Allow submodules from local paths, which Git refuses by default
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	libDir := filepath.Join(tempDir, "lib")
+	if err := os.Mkdir(libDir, 0755); err != nil {
+		t.Fatalf("Failed to create %s: %v", libDir, err)
+	}
+	libUpstream, libWork := setupUpstream(t, libDir)
+	testutil.CommitFiles(t, libWork, map[string]string{"lib.go": "package lib"})
+	testutil.Git(t, libWork, "push", "-q", "origin", "HEAD:main")
+
+	testutil.Git(t, work, "submodule", "add", "-q", libUpstream, "lib")
+	testutil.Git(t, work, "commit", "-q", "-m", "Add submodule")
+	testutil.Git(t, work, "push", "-q", "origin", "HEAD:main")
+
+	gitOps := NewGitOpsWithOptions(false, Options{Submodules: true})
+	baseline := filepath.Join(tempDir, "baseline")
+	repo := types.Repository{Name: "api", Owner: "acme", FullName: "acme/api", CloneURL: upstream}
+	result := gitOps.CloneRepository(repo, baseline)
+	if !result.Success || result.SubmoduleError != nil {
+		t.Fatalf("CloneRepository failed: %v (submodules: %v)", result.Error, result.SubmoduleError)
+	}
+	repoPath := gitOps.RepositoryPath(repo, baseline)
+	defer gitOps.setWritePermissions(repoPath)
+
+	info, err := os.Stat(filepath.Join(repoPath, "lib", "lib.go"))
+	if err != nil {
+		t.Fatalf("Expected submodule to be checked out: %v", err)
+	}
+	if info.Mode().Perm()&0200 != 0 {
+		t.Error("Submodule files should be read-only")
+	}
+
+	// A broken submodule is reported without failing the repository
+	if err := os.Rename(libUpstream, libUpstream+".moved"); err != nil {
+		t.Fatalf("Failed to move submodule upstream: %v", err)
+	}
+	testutil.Git(t, work, "-C", "lib", "commit", "-q", "--allow-empty", "-m", "Unreachable")
+	testutil.Git(t, work, "commit", "-q", "-am", "Update submodule")
+	testutil.Git(t, work, "push", "-q", "origin", "HEAD:main")
+
+	update := gitOps.UpdateRepository(repo, baseline)
+	if !update.Success || update.Error != nil || update.SubmoduleError == nil {
+		t.Errorf("Expected update to succeed with a submodule error, got %+v", update)
+	}
+}
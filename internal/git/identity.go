@@ -0,0 +1,177 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jonasbn/baseline/internal/layout"
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// Relocation describes a repository renamed or transferred upstream, whose
+// existing clone was moved to the path of its new name
+type Relocation struct {
+	Repository types.Repository
+	OldPath    string
+	NewPath    string
+	// Duplicate marks a further clone at OldPath of a repository whose clone is
+	// already at, or moved to, NewPath. Duplicates are reported, never moved.
+	Duplicate bool
+	Error     error
+}
+
+// identityKey identifies a repository by its source, host and the stable ID assigned by the source
+func identityKey(source, host, id string) string {
+	return source + "|" + strings.ToLower(host) + "|" + id
+}
+
+// recordIdentity stores the stable ID of the repository in its configuration,
+// so the clone is found again after the repository was renamed or transferred
+func (g *GitOps) recordIdentity(repoPath string, repo types.Repository) error {
+	if repo.ID == "" {
+		return nil
+	}
+
+	recorded, _ := g.git(repoPath, "config", "--get", "baseline.id")
+	if recorded == repo.ID {
+		return nil
+	}
+
+	for key, value := range map[string]string{
+		"baseline.id":     repo.ID,
+		"baseline.source": repo.Source,
+		"baseline.host":   layout.Host(repo),
+	} {
+		if _, err := g.git(repoPath, "config", key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// indexRepositories returns the paths of the repositories below targetDir with a
+// recorded ID, keyed by identityKey. Several clones may share an ID, e.g. a stale
+// copy left behind, so all their paths are kept.
+func (g *GitOps) indexRepositories(targetDir string) (map[string][]string, error) {
+	paths, err := FindRepositories(targetDir)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string][]string)
+	for _, repoPath := range paths {
+		output, err := g.git(repoPath, "config", "--get-regexp", `^baseline\.(id|source|host)$`)
+		if err != nil {
+			// Repositories without a recorded ID are not indexed
+			continue
+		}
+
+		values := make(map[string]string)
+		for _, line := range strings.Split(output, "\n") {
+			if key, value, ok := strings.Cut(line, " "); ok {
+				values[key] = value
+			}
+		}
+
+		if id := values["baseline.id"]; id != "" {
+			key := identityKey(values["baseline.source"], values["baseline.host"], id)
+			index[key] = append(index[key], repoPath)
+		}
+	}
+
+	return index, nil
+}
+
+// FindRenamed finds existing clones of repositories that were renamed or transferred
+// upstream by their recorded ID, without moving them. Of several clones with the same
+// ID the one at the expected path is kept, otherwise the first one found is moved;
+// the others are returned as duplicates.
+func (g *GitOps) FindRenamed(repositories []types.Repository, targetDir string) ([]Relocation, error) {
+	// Scanning the baseline is only needed if the source assigns IDs
+	hasIDs := false
+	for _, repo := range repositories {
+		hasIDs = hasIDs || repo.ID != ""
+	}
+	if _, err := os.Stat(targetDir); err != nil || !hasIDs {
+		return nil, nil
+	}
+
+	index, err := g.indexRepositories(targetDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", targetDir, err)
+	}
+
+	var relocations []Relocation
+	for _, repo := range repositories {
+		if repo.ID == "" {
+			continue
+		}
+
+		paths := index[identityKey(repo.Source, layout.Host(repo), repo.ID)]
+		if len(paths) == 0 {
+			continue
+		}
+
+		newPath := g.RepositoryPath(repo, targetDir)
+		kept := paths[0]
+		if slices.Contains(paths, newPath) {
+			kept = newPath
+		} else {
+			relocations = append(relocations, Relocation{Repository: repo, OldPath: kept, NewPath: newPath})
+		}
+
+		for _, path := range paths {
+			if path != kept {
+				relocations = append(relocations, Relocation{Repository: repo, OldPath: path, NewPath: newPath, Duplicate: true})
+			}
+		}
+	}
+
+	return relocations, nil
+}
+
+// RelocateRenamed moves the existing clones of repositories renamed or transferred
+// upstream to the path of the new name, pointing their origin remote at the new URL.
+// Repositories whose new path already exists are left alone and reported with an error,
+// duplicates are left alone as well.
+func (g *GitOps) RelocateRenamed(repositories []types.Repository, targetDir string) ([]Relocation, error) {
+	relocations, err := g.FindRenamed(repositories, targetDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, r := range relocations {
+		if r.Duplicate {
+			continue
+		}
+		if _, err := os.Stat(r.NewPath); err == nil {
+			relocations[i].Error = fmt.Errorf("cannot move %s, %s already exists", r.OldPath, r.NewPath)
+			continue
+		}
+		relocations[i].Error = g.relocate(r.Repository, r.OldPath, r.NewPath, targetDir)
+	}
+
+	return relocations, nil
+}
+
+// relocate moves the clone at oldPath to newPath and updates its origin remote
+func (g *GitOps) relocate(repo types.Repository, oldPath, newPath, targetDir string) error {
+	if err := g.MoveRepository(oldPath, newPath, targetDir); err != nil {
+		return err
+	}
+
+	if err := g.setWritePermissions(newPath); err != nil {
+		return fmt.Errorf("failed to set write permissions for %s: %w", newPath, err)
+	}
+	defer g.setReadOnlyPermissions(newPath)
+
+	if _, err := g.git(newPath, "remote", "set-url", "origin", repo.CloneURL); err != nil {
+		return fmt.Errorf("failed to update origin of %s: %w", filepath.Base(newPath), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+func TestRelocateRenamed(t *testing.T) {
+	gitOps := NewGitOps(false)
+	tempDir := t.TempDir()
+	upstream, _ := setupUpstream(t, tempDir)
+
+	baseline := filepath.Join(tempDir, "baseline")
+	repo := types.Repository{ID: "42", Name: "api", Owner: "acme", FullName: "acme/api", CloneURL: upstream, Source: "github"}
+	if result := gitOps.CloneRepository(repo, baseline); !result.Success {
+		t.Fatalf("CloneRepository failed: %v", result.Error)
+	}
+
+	// The repository is transferred to another owner and renamed
+	renamedURL := filepath.Join(tempDir, "platform", "gateway.git")
+	if err := os.MkdirAll(filepath.Dir(renamedURL), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.Rename(upstream, renamedURL); err != nil {
+		t.Fatalf("Failed to rename upstream: %v", err)
+	}
+	renamed := types.Repository{ID: "42", Name: "gateway", Owner: "platform", FullName: "platform/gateway", CloneURL: renamedURL, Source: "github"}
+	other := types.Repository{ID: "43", Name: "web", Owner: "acme", FullName: "acme/web", CloneURL: renamedURL, Source: "github"}
+
+	relocations, err := gitOps.RelocateRenamed([]types.Repository{renamed, other}, baseline)
+	if err != nil {
+		t.Fatalf("RelocateRenamed failed: %v", err)
+	}
+
+	newPath := gitOps.RepositoryPath(renamed, baseline)
+	defer gitOps.setWritePermissions(newPath)
+
+	if len(relocations) != 1 || relocations[0].Error != nil || relocations[0].NewPath != newPath {
+		t.Fatalf("Expected one relocation to %s, got %+v", newPath, relocations)
+	}
+
+	if err := gitOps.CheckOrigin(renamed, newPath); err != nil {
+		t.Errorf("Expected origin to point at the new URL: %v", err)
+	}
+
+	if gitOps.RepositoryExists(repo, baseline) {
+		t.Error("Expected the old path to be gone")
+	}
+
+	// A second run finds nothing to move
+	if relocations, err := gitOps.RelocateRenamed([]types.Repository{renamed}, baseline); err != nil || len(relocations) != 0 {
+		t.Errorf("Expected no relocations, got %+v (%v)", relocations, err)
+	}
+}
+
+func TestRelocateRenamedDuplicates(t *testing.T) {
+	gitOps := NewGitOps(false)
+	tempDir := t.TempDir()
+	upstream, _ := setupUpstream(t, tempDir)
+
+	// A stale second clone of the same repository is left in the baseline
+	baseline := filepath.Join(tempDir, "baseline")
+	repo := types.Repository{ID: "42", Name: "api", Owner: "acme", FullName: "acme/api", CloneURL: upstream, Source: "github"}
+	stale := types.Repository{ID: "42", Name: "api-old", Owner: "acme", FullName: "acme/api-old", CloneURL: upstream, Source: "github"}
+	for _, r := range []types.Repository{repo, stale} {
+		if result := gitOps.CloneRepository(r, baseline); !result.Success {
+			t.Fatalf("CloneRepository failed: %v", result.Error)
+		}
+	}
+	stalePath := gitOps.RepositoryPath(stale, baseline)
+	defer gitOps.setWritePermissions(stalePath)
+
+	// The clone at the expected path is kept
+	relocations, err := gitOps.RelocateRenamed([]types.Repository{repo}, baseline)
+	if err != nil {
+		t.Fatalf("RelocateRenamed failed: %v", err)
+	}
+	if len(relocations) != 1 || !relocations[0].Duplicate || relocations[0].OldPath != stalePath {
+		t.Fatalf("Expected the stale clone to be reported as duplicate, got %+v", relocations)
+	}
+	if !gitOps.RepositoryExists(repo, baseline) || !gitOps.RepositoryExists(stale, baseline) {
+		t.Error("Expected both clones to stay in place")
+	}
+
+	// After a rename the first clone is moved and the duplicate stays where it is
+	renamed := types.Repository{ID: "42", Name: "gateway", Owner: "acme", FullName: "acme/gateway", CloneURL: upstream, Source: "github"}
+	newPath := gitOps.RepositoryPath(renamed, baseline)
+	defer gitOps.setWritePermissions(newPath)
+	for run := 0; run < 2; run++ {
+		relocations, err = gitOps.RelocateRenamed([]types.Repository{renamed}, baseline)
+		if err != nil {
+			t.Fatalf("RelocateRenamed failed: %v", err)
+		}
+
+		var moved, duplicates int
+		for _, r := range relocations {
+			switch {
+			case r.Duplicate && r.OldPath == stalePath:
+				duplicates++
+			case !r.Duplicate && r.Error == nil && r.NewPath == newPath:
+				moved++
+			default:
+				t.Errorf("Unexpected relocation %+v", r)
+			}
+		}
+		if duplicates != 1 || moved != 1-run {
+			t.Errorf("Run %d: expected %d move and one duplicate, got %+v", run+1, 1-run, relocations)
+		}
+	}
+	if !gitOps.RepositoryExists(stale, baseline) {
+		t.Error("Expected the duplicate not to be moved")
+	}
+}
@@ -0,0 +1,122 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// LFSMode selects how Git LFS content is handled
+type LFSMode string
+
+const (
+	// LFSDefault leaves LFS to the Git configuration of the user
+	LFSDefault LFSMode = ""
+	// LFSSkip disables the LFS filters, git-lfs is never run
+	LFSSkip LFSMode = "skip"
+	// LFSPointers keeps the pointer files in the working tree without downloading content
+	LFSPointers LFSMode = "pointers"
+	// LFSFetch downloads the content of all LFS files
+	LFSFetch LFSMode = "fetch"
+	// LFSFetchInclude downloads the content of the LFS files matching a pattern
+	LFSFetchInclude LFSMode = "fetch-include"
+)
+
+// LFSPolicy selects how Git LFS content is handled on clone and update
+type LFSPolicy struct {
+	Mode LFSMode
+	// Include is the pattern of the files to download with LFSFetchInclude
+	Include string
+}
+
+// ParseLFSPolicy parses a policy given on the command line: skip, pointers,
+// fetch or fetch-include=<glob>
+func ParseLFSPolicy(s string) (LFSPolicy, error) {
+	if include, ok := strings.CutPrefix(s, string(LFSFetchInclude)+"="); ok {
+		if include == "" {
+			return LFSPolicy{}, fmt.Errorf("missing pattern in LFS policy %q", s)
+		}
+		return LFSPolicy{Mode: LFSFetchInclude, Include: include}, nil
+	}
+
+	switch LFSMode(s) {
+	case LFSDefault, LFSSkip, LFSPointers, LFSFetch:
+		return LFSPolicy{Mode: LFSMode(s)}, nil
+	}
+	return LFSPolicy{}, fmt.Errorf("unsupported LFS policy %q, use skip, pointers, fetch or fetch-include=<glob>", s)
+}
+
+// RequiresLFS reports whether the policy needs git-lfs to be installed
+func (p LFSPolicy) RequiresLFS() bool {
+	return p.Mode == LFSFetch || p.Mode == LFSFetchInclude
+}
+
+// String returns the policy as given on the command line
+func (p LFSPolicy) String() string {
+	if p.Mode == LFSFetchInclude {
+		return string(p.Mode) + "=" + p.Include
+	}
+	return string(p.Mode)
+}
+
+// LFSVersion returns the version of the installed git-lfs, or an error if it is not installed
+func LFSVersion() (string, error) {
+	output, err := exec.Command("git", "lfs", "version").Output()
+	if err != nil {
+		return "", fmt.Errorf("git-lfs is not installed")
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// command returns a Git command applying the LFS policy. LFS content is never
+// downloaded while checking out, it is pulled afterwards by pullLFS, so LFS
+// failures are reported apart from Git failures.
+func (g *GitOps) command(args ...string) *exec.Cmd {
+	switch g.lfs.Mode {
+	case LFSSkip:
+		// Empty filter drivers are not run, the pointer files are checked out as they are
+		args = append([]string{
+			"-c", "filter.lfs.process=",
+			"-c", "filter.lfs.smudge=",
+			"-c", "filter.lfs.clean=",
+			"-c", "filter.lfs.required=false",
+		}, args...)
+	}
+
+	cmd := exec.Command("git", args...)
+	if g.lfs.Mode != LFSDefault && g.lfs.Mode != LFSSkip {
+		cmd.Env = append(os.Environ(), "GIT_LFS_SKIP_SMUDGE=1")
+	}
+	return cmd
+}
+
+// pullLFS downloads the LFS content selected by the policy into the checkout at repoPath
+func (g *GitOps) pullLFS(repoPath string) error {
+	var err error
+	switch g.lfs.Mode {
+	case LFSFetch:
+		_, err = g.git(repoPath, "lfs", "pull")
+	case LFSFetchInclude:
+		_, err = g.git(repoPath, "lfs", "pull", "--include="+g.lfs.Include)
+	case LFSDefault:
+		// Without git-lfs the pointer files are checked out in place of the content
+		if g.lfsMissing && g.usesLFS(repoPath) {
+			return fmt.Errorf("repository uses Git LFS but git-lfs is not installed, pointer files were checked out")
+		}
+		return nil
+	default:
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to pull LFS content: %w", err)
+	}
+	return nil
+}
+
+// usesLFS reports whether a .gitattributes file of the HEAD commit assigns the LFS filter
+func (g *GitOps) usesLFS(repoPath string) bool {
+	_, err := g.git(repoPath, "grep", "-q", "-F", "filter=lfs", "HEAD", "--", "*.gitattributes")
+	return err == nil
+}
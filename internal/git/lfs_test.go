@@ -0,0 +1,120 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jonasbn/baseline/internal/testutil"
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+func TestParseLFSPolicy(t *testing.T) {
+	cases := map[string]LFSPolicy{
+		"":                      {},
+		"skip":                  {Mode: LFSSkip},
+		"pointers":              {Mode: LFSPointers},
+		"fetch":                 {Mode: LFSFetch},
+		"fetch-include=*.psd":   {Mode: LFSFetchInclude, Include: "*.psd"},
+		"fetch-include=a/*,b/*": {Mode: LFSFetchInclude, Include: "a/*,b/*"},
+	}
+
+	for spec, expected := range cases {
+		policy, err := ParseLFSPolicy(spec)
+		if err != nil || policy != expected {
+			t.Errorf("Expected %+v for %q, got %+v (%v)", expected, spec, policy, err)
+		}
+		if err == nil && policy.String() != spec {
+			t.Errorf("Expected %q to round-trip, got %q", spec, policy.String())
+		}
+	}
+
+	for _, spec := range []string{"all", "fetch-include=", "fetch-include"} {
+		if _, err := ParseLFSPolicy(spec); err == nil {
+			t.Errorf("Expected ParseLFSPolicy(%q) to fail", spec)
+		}
+	}
+}
+
+func TestLFSCommand(t *testing.T) {
+	skip := NewGitOpsWithOptions(false, Options{LFS: LFSPolicy{Mode: LFSSkip}})
+	if cmd := skip.command("status"); !slices.Contains(cmd.Args, "filter.lfs.smudge=") || cmd.Env != nil {
+		t.Errorf("Expected skip to disable the LFS filters, got %v", cmd.Args)
+	}
+
+	pointers := NewGitOpsWithOptions(false, Options{LFS: LFSPolicy{Mode: LFSPointers}})
+	if cmd := pointers.command("status"); !slices.Contains(cmd.Env, "GIT_LFS_SKIP_SMUDGE=1") {
+		t.Error("Expected pointers to skip the smudge filter")
+	}
+
+	if cmd := NewGitOps(false).command("status"); cmd.Env != nil || len(cmd.Args) != 2 {
+		t.Errorf("Expected the default policy to leave Git alone, got %v", cmd.Args)
+	}
+}
+
+// hideLFS runs the test with a PATH holding only git, so git-lfs is not found
+func hideLFS(t *testing.T) {
+	t.Helper()
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Fatalf("git not found: %v", err)
+	}
+	bin := t.TempDir()
+	if err := os.Symlink(gitPath, filepath.Join(bin, "git")); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	if _, err := LFSVersion(); err == nil {
+		t.Fatal("Expected git-lfs to be hidden")
+	}
+}
+
+func TestLFSErrorIsSeparate(t *testing.T) {
+	tempDir := t.TempDir()
+	upstream, _ := setupUpstream(t, tempDir)
+	hideLFS(t)
+
+	gitOps := NewGitOpsWithOptions(false, Options{LFS: LFSPolicy{Mode: LFSFetch}})
+	baseline := filepath.Join(tempDir, "baseline")
+	repo := types.Repository{Name: "api", Owner: "acme", FullName: "acme/api", CloneURL: upstream}
+	result := gitOps.CloneRepository(repo, baseline)
+	defer gitOps.setWritePermissions(gitOps.RepositoryPath(repo, baseline))
+
+	if !result.Success || result.Error != nil || result.LFSError == nil {
+		t.Errorf("Expected clone to succeed with an LFS error, got %+v", result)
+	}
+}
+
+func TestLFSMissing(t *testing.T) {
+	tempDir := t.TempDir()
+	upstream, work := setupUpstream(t, tempDir)
+	hideLFS(t)
+
+	// Repositories without LFS are not affected by the missing git-lfs
+	gitOps := NewGitOpsWithOptions(false, Options{LFSMissing: true})
+	baseline := filepath.Join(tempDir, "baseline")
+	repo := types.Repository{Name: "api", Owner: "acme", FullName: "acme/api", CloneURL: upstream}
+	result := gitOps.CloneRepository(repo, baseline)
+	defer gitOps.setWritePermissions(gitOps.RepositoryPath(repo, baseline))
+	if !result.Success || result.LFSError != nil {
+		t.Fatalf("Expected clone to succeed without LFS error, got %+v", result)
+	}
+
+	testutil.CommitFiles(t, work, map[string]string{".gitattributes": "*.bin filter=lfs diff=lfs merge=lfs -text\n"})
+	testutil.Git(t, work, "push", "-q", "origin", "HEAD:main")
+
+	update := gitOps.UpdateRepository(repo, baseline)
+	if update.Error != nil || update.LFSError == nil || !strings.Contains(update.LFSError.Error(), "git-lfs is not installed") {
+		t.Errorf("Expected an LFS error for a repository using LFS, got %+v", update)
+	}
+
+	// Keeping pointer files is what the pointers policy asks for
+	pointers := NewGitOpsWithOptions(false, Options{LFS: LFSPolicy{Mode: LFSPointers}, LFSMissing: true})
+	if update := pointers.UpdateRepository(repo, baseline); update.Error != nil || update.LFSError != nil {
+		t.Errorf("Expected no LFS error with the pointers policy, got %+v", update)
+	}
+}
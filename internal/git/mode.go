@@ -0,0 +1,136 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// Mode selects how repositories are stored in the baseline
+type Mode string
+
+const (
+	// ModeCheckout stores a clone with a working tree of the default branch
+	ModeCheckout Mode = "checkout"
+	// ModeMirror stores a bare mirror holding every branch and tag
+	ModeMirror Mode = "mirror"
+)
+
+// ParseMode validates a mode given on the command line
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case ModeCheckout, ModeMirror:
+		return Mode(s), nil
+	}
+	return "", fmt.Errorf("unsupported mode %q, use checkout or mirror", s)
+}
+
+// DetectMode reports the mode of the repository stored at repoPath
+func DetectMode(repoPath string) (Mode, error) {
+	if _, err := os.Lstat(filepath.Join(repoPath, ".git")); err == nil {
+		return ModeCheckout, nil
+	}
+	if isBareRepository(repoPath) {
+		return ModeMirror, nil
+	}
+	return "", fmt.Errorf("%s is not a Git repository", repoPath)
+}
+
+// isBareRepository reports whether path looks like a bare Git repository
+func isBareRepository(path string) bool {
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(path, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckMode verifies that the existing repository at repoPath was created in the
+// mode of this run, so a checkout is never updated as a mirror or the other way round
+func (g *GitOps) CheckMode(repoPath string) error {
+	mode, err := DetectMode(repoPath)
+	if err != nil {
+		// Without a repository there is nothing to compare against
+		return nil
+	}
+
+	if mode != g.mode {
+		return fmt.Errorf("%s is stored in %s mode, use --convert-mode to convert it to %s mode", repoPath, mode, g.mode)
+	}
+
+	return nil
+}
+
+// ConvertRepository replaces the existing repository of repo with a fresh clone in
+// the mode of this run. The new clone is made next to the old one and only swapped
+// in once it succeeded, so a failed conversion leaves the repository as it was. The
+// old repository is moved aside during the swap and removed once the new clone is in
+// its place.
+func (g *GitOps) ConvertRepository(repo types.Repository, targetDir string) types.CloneResult {
+	start := time.Now()
+	repoPath := g.RepositoryPath(repo, targetDir)
+	tempPath := repoPath + ".baseline-convert"
+	oldPath := repoPath + ".baseline-old"
+
+	// Remove leftovers of an interrupted conversion, restoring the old repository if
+	// it was moved aside but never replaced
+	if _, err := os.Stat(tempPath); err == nil {
+		g.setWritePermissions(tempPath)
+		os.RemoveAll(tempPath)
+	}
+	if _, err := os.Stat(oldPath); err == nil {
+		if _, err := os.Stat(repoPath); err == nil {
+			g.setWritePermissions(oldPath)
+			os.RemoveAll(oldPath)
+		} else if err := g.MoveRepository(oldPath, repoPath, targetDir); err != nil {
+			return types.CloneResult{Repository: repo, Error: fmt.Errorf("failed to restore %s: %w", repoPath, err), Duration: time.Since(start)}
+		}
+	}
+
+	if g.verbose {
+		fmt.Printf("Converting %s to %s mode\n", repo.FullName, g.mode)
+	}
+
+	result := g.cloneTo(repo, tempPath)
+	if !result.Success {
+		g.setWritePermissions(tempPath)
+		os.RemoveAll(tempPath)
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	if err := g.MoveRepository(repoPath, oldPath, targetDir); err != nil {
+		result.Success = false
+		result.Error = err
+	} else if err := g.MoveRepository(tempPath, repoPath, targetDir); err != nil {
+		// Put the old repository back and drop the new clone
+		g.MoveRepository(oldPath, repoPath, targetDir)
+		g.setWritePermissions(tempPath)
+		os.RemoveAll(tempPath)
+		result.Success = false
+		result.Error = err
+	} else {
+		g.setWritePermissions(oldPath)
+		if err := os.RemoveAll(oldPath); err != nil {
+			result.Success = false
+			result.Error = fmt.Errorf("failed to remove %s: %w", oldPath, err)
+		}
+	}
+
+	result.Duration = time.Since(start)
+	return result
+}
+
+// listRefs returns all refs of the repository with the objects they point to
+func (g *GitOps) listRefs(repoPath string) (string, error) {
+	refs, err := g.git(repoPath, "for-each-ref", "--format=%(objectname) %(refname)")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(refs), nil
+}
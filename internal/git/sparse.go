@@ -0,0 +1,136 @@
+package git
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// Sparse selects the paths present in the working tree of a checkout
+type Sparse struct {
+	// Patterns are directories in cone mode, gitignore-style patterns otherwise
+	Patterns []string
+	// NoCone selects gitignore-style patterns instead of directories
+	NoCone bool
+}
+
+// SparseRule applies sparse patterns to the repositories whose full name matches Pattern
+type SparseRule struct {
+	Pattern string
+	Sparse  Sparse
+}
+
+// IsEmpty reports whether the full working tree is checked out
+func (s Sparse) IsEmpty() bool {
+	return len(s.Patterns) == 0
+}
+
+// key identifies the pattern set, it is recorded in the repository configuration
+// to detect when the patterns change
+func (s Sparse) key() string {
+	if s.IsEmpty() {
+		return ""
+	}
+
+	mode := "cone"
+	if s.NoCone {
+		mode = "no-cone"
+	}
+	sum := sha256.Sum256([]byte(mode + "\n" + strings.Join(s.Patterns, "\n")))
+	return mode + ":" + hex.EncodeToString(sum[:8])
+}
+
+// ParseSparseRule parses per-repository sparse patterns given as pattern:paths, where
+// pattern is a glob matched against the full repository name and paths is a comma
+// separated list of directories, e.g. "acme/monorepo:src,docs". Prefix the paths with
+// "no-cone:" for gitignore-style patterns, or use "full" for the full working tree.
+func ParseSparseRule(spec string) (SparseRule, error) {
+	pattern, paths, ok := strings.Cut(spec, ":")
+	if !ok || pattern == "" || paths == "" {
+		return SparseRule{}, fmt.Errorf("invalid sparse rule %q, expected pattern:paths", spec)
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return SparseRule{}, fmt.Errorf("invalid pattern in sparse rule %q: %w", spec, err)
+	}
+
+	rule := SparseRule{Pattern: pattern}
+	if paths == "full" {
+		return rule, nil
+	}
+
+	if rest, ok := strings.CutPrefix(paths, "no-cone:"); ok {
+		rule.Sparse.NoCone = true
+		paths = rest
+	}
+
+	for _, p := range strings.Split(paths, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			rule.Sparse.Patterns = append(rule.Sparse.Patterns, p)
+		}
+	}
+
+	if rule.Sparse.IsEmpty() {
+		return SparseRule{}, fmt.Errorf("invalid sparse rule %q, no paths given", spec)
+	}
+
+	return rule, nil
+}
+
+// sparseFor returns the sparse patterns of the first rule matching the repository,
+// falling back to the patterns of the run. It reports whether the patterns were asked
+// for, by a matching rule or the patterns of the run, rather than left unset.
+func (g *GitOps) sparseFor(repo types.Repository) (Sparse, bool) {
+	for _, rule := range g.sparseRules {
+		if matched, _ := path.Match(rule.Pattern, repo.FullName); matched {
+			return rule.Sparse, true
+		}
+	}
+	return g.sparse, !g.sparse.IsEmpty()
+}
+
+// applySparse makes the working tree of the checkout at repoPath match the sparse
+// patterns of the repository, if they differ from the recorded ones. It reports
+// whether the working tree changed. Repositories no rule or pattern of the run applies
+// to keep their patterns, only a matching "full" rule restores the full working tree.
+func (g *GitOps) applySparse(repo types.Repository, repoPath string) (bool, error) {
+	sparse, explicit := g.sparseFor(repo)
+	if !explicit {
+		return false, nil
+	}
+	recorded, _ := g.git(repoPath, "config", "--get", "baseline.sparse")
+	if sparse.key() == recorded {
+		return false, nil
+	}
+
+	if sparse.IsEmpty() {
+		if _, err := g.git(repoPath, "sparse-checkout", "disable"); err != nil {
+			return false, err
+		}
+		if _, err := g.git(repoPath, "config", "--unset", "baseline.sparse"); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	args := []string{"sparse-checkout", "set"}
+	if sparse.NoCone {
+		args = append(args, "--no-cone")
+	} else {
+		args = append(args, "--cone")
+	}
+	args = append(args, sparse.Patterns...)
+	if _, err := g.git(repoPath, args...); err != nil {
+		return false, err
+	}
+
+	if _, err := g.git(repoPath, "config", "baseline.sparse", sparse.key()); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,97 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jonasbn/baseline/internal/testutil"
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+func TestParseSparseRule(t *testing.T) {
+	rule, err := ParseSparseRule("acme/mono*:src, docs")
+	if err != nil {
+		t.Fatalf("ParseSparseRule failed: %v", err)
+	}
+
+	expected := SparseRule{Pattern: "acme/mono*", Sparse: Sparse{Patterns: []string{"src", "docs"}}}
+	if !reflect.DeepEqual(rule, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, rule)
+	}
+
+	rule, err = ParseSparseRule("acme/web:no-cone:/*.md,!/vendor/")
+	if err != nil || !rule.Sparse.NoCone || len(rule.Sparse.Patterns) != 2 {
+		t.Errorf("Expected a non-cone rule with two patterns, got %+v (%v)", rule, err)
+	}
+
+	if rule, err := ParseSparseRule("acme/api:full"); err != nil || !rule.Sparse.IsEmpty() {
+		t.Errorf("Expected a rule for the full working tree, got %+v (%v)", rule, err)
+	}
+
+	for _, spec := range []string{"acme/api", "acme/api:", "acme/api:no-cone:", "[:src"} {
+		if _, err := ParseSparseRule(spec); err == nil {
+			t.Errorf("Expected ParseSparseRule(%q) to fail", spec)
+		}
+	}
+}
+
+func TestSparseCheckout(t *testing.T) {
+	tempDir := t.TempDir()
+	upstream, work := setupUpstream(t, tempDir)
+	testutil.CommitFiles(t, work, map[string]string{"src/file.txt": "src", "docs/file.txt": "docs", "vendor/file.txt": "vendor"})
+	testutil.Git(t, work, "push", "-q", "origin", "HEAD:main")
+
+	gitOps := NewGitOpsWithOptions(false, Options{Sparse: Sparse{Patterns: []string{"src"}}})
+	baseline := filepath.Join(tempDir, "baseline")
+	repo := types.Repository{Name: "api", Owner: "acme", FullName: "acme/api", CloneURL: upstream}
+	if result := gitOps.CloneRepository(repo, baseline); !result.Success {
+		t.Fatalf("CloneRepository failed: %v", result.Error)
+	}
+	repoPath := gitOps.RepositoryPath(repo, baseline)
+	defer gitOps.setWritePermissions(repoPath)
+
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(repoPath, name))
+		return err == nil
+	}
+
+	if !exists("src/file.txt") || !exists("README.md") || exists("docs") || exists("vendor") {
+		t.Error("Expected only src and the files in the root directory to be checked out")
+	}
+
+	// Changed patterns are re-applied on update
+	widened := NewGitOpsWithOptions(false, Options{
+		SparseRules: []SparseRule{{Pattern: "acme/*", Sparse: Sparse{Patterns: []string{"src", "docs"}}}},
+	})
+	if result := widened.UpdateRepository(repo, baseline); result.Error != nil || !result.Updated {
+		t.Fatalf("Expected changed sparse patterns to update the repository, got %+v", result)
+	}
+	if !exists("docs/file.txt") || exists("vendor") {
+		t.Error("Expected docs to be added to the working tree")
+	}
+
+	if result := widened.UpdateRepository(repo, baseline); result.Error != nil || result.Updated {
+		t.Errorf("Expected unchanged sparse patterns not to update the repository, got %+v", result)
+	}
+
+	// Runs without sparse options leave the patterns alone
+	if result := NewGitOps(false).UpdateRepository(repo, baseline); result.Error != nil || exists("vendor") {
+		t.Errorf("Expected the sparse checkout to be kept, got %+v", result)
+	}
+
+	// So do rules matching other repositories
+	other := NewGitOpsWithOptions(false, Options{
+		SparseRules: []SparseRule{{Pattern: "other/*", Sparse: Sparse{Patterns: []string{"vendor"}}}},
+	})
+	if result := other.UpdateRepository(repo, baseline); result.Error != nil || exists("vendor") || !exists("docs/file.txt") {
+		t.Errorf("Expected the sparse checkout to be kept, got %+v", result)
+	}
+
+	// A matching full rule restores the full working tree
+	full := NewGitOpsWithOptions(false, Options{SparseRules: []SparseRule{{Pattern: "acme/api"}}})
+	if result := full.UpdateRepository(repo, baseline); result.Error != nil || !exists("vendor/file.txt") {
+		t.Errorf("Expected the full working tree, got %+v", result)
+	}
+}
@@ -0,0 +1,161 @@
+package git
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// Strategy selects how much history and which objects are cloned
+type Strategy struct {
+	// Depth limits the history to the given number of commits, 0 clones the full history
+	Depth int
+	// ShallowSince limits the history to commits after the given date
+	ShallowSince string
+	// Filter requests a partial clone, e.g. blob:none (blobless) or tree:0 (treeless)
+	Filter string
+}
+
+// StrategyRule applies a strategy to the repositories whose full name matches Pattern
+type StrategyRule struct {
+	Pattern  string
+	Strategy Strategy
+}
+
+// IsShallow reports whether the strategy limits the history
+func (s Strategy) IsShallow() bool {
+	return s.Depth > 0 || s.ShallowSince != ""
+}
+
+// Validate checks that the options of the strategy can be combined
+func (s Strategy) Validate() error {
+	if s.Depth < 0 {
+		return fmt.Errorf("depth must not be negative")
+	}
+	if s.Depth > 0 && s.ShallowSince != "" {
+		return fmt.Errorf("depth and shallow-since cannot be combined")
+	}
+	if s.Filter != "" && !validFilter(s.Filter) {
+		return fmt.Errorf("unsupported filter %q, use blob:none, blob:limit=<size> or tree:<depth>", s.Filter)
+	}
+	return nil
+}
+
+// validFilter accepts the object filters useful for a baseline
+func validFilter(filter string) bool {
+	switch {
+	case filter == "blob:none":
+		return true
+	case strings.HasPrefix(filter, "blob:limit="):
+		return len(filter) > len("blob:limit=")
+	case strings.HasPrefix(filter, "tree:"):
+		_, err := strconv.Atoi(strings.TrimPrefix(filter, "tree:"))
+		return err == nil
+	}
+	return false
+}
+
+// cloneArgs returns the git clone arguments for the strategy
+func (s Strategy) cloneArgs() []string {
+	var args []string
+	if s.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(s.Depth))
+	}
+	if s.ShallowSince != "" {
+		args = append(args, "--shallow-since="+s.ShallowSince)
+	}
+	if s.Filter != "" {
+		args = append(args, "--filter="+s.Filter)
+	}
+	return args
+}
+
+// ParseStrategyRule parses a per-repository strategy given as pattern:options, where
+// pattern is a glob matched against the full repository name and options is a comma
+// separated list of depth=N, shallow-since=DATE and filter=SPEC, or "full" for a
+// complete clone, e.g. "acme/monorepo:depth=1,filter=blob:none"
+func ParseStrategyRule(spec string) (StrategyRule, error) {
+	pattern, options, ok := strings.Cut(spec, ":")
+	if !ok || pattern == "" || options == "" {
+		return StrategyRule{}, fmt.Errorf("invalid strategy %q, expected pattern:options", spec)
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return StrategyRule{}, fmt.Errorf("invalid pattern in strategy %q: %w", spec, err)
+	}
+
+	var strategy Strategy
+	for _, option := range strings.Split(options, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+		switch key {
+		case "full":
+			strategy = Strategy{}
+		case "depth":
+			depth, err := strconv.Atoi(value)
+			if err != nil {
+				return StrategyRule{}, fmt.Errorf("invalid depth in strategy %q: %w", spec, err)
+			}
+			strategy.Depth = depth
+		case "shallow-since":
+			strategy.ShallowSince = value
+		case "filter":
+			strategy.Filter = value
+		default:
+			return StrategyRule{}, fmt.Errorf("unknown option %q in strategy %q", key, spec)
+		}
+	}
+
+	if err := strategy.Validate(); err != nil {
+		return StrategyRule{}, fmt.Errorf("invalid strategy %q: %w", spec, err)
+	}
+
+	return StrategyRule{Pattern: pattern, Strategy: strategy}, nil
+}
+
+// strategyFor returns the strategy of the first rule matching the repository,
+// falling back to the strategy of the run
+func (g *GitOps) strategyFor(repo types.Repository) Strategy {
+	for _, rule := range g.strategyRules {
+		if matched, _ := path.Match(rule.Pattern, repo.FullName); matched {
+			return rule.Strategy
+		}
+	}
+	return g.strategy
+}
+
+// recordStrategy stores the shallow options in the repository configuration, so
+// later updates keep the repository as shallow as it was cloned
+func (g *GitOps) recordStrategy(repoPath string, strategy Strategy) error {
+	if strategy.Depth > 0 {
+		if _, err := g.git(repoPath, "config", "baseline.depth", strconv.Itoa(strategy.Depth)); err != nil {
+			return err
+		}
+	}
+	if strategy.ShallowSince != "" {
+		if _, err := g.git(repoPath, "config", "baseline.shallowSince", strategy.ShallowSince); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shallowFetchArgs returns the git fetch arguments keeping a shallow repository
+// shallow, using the options recorded when it was cloned
+func (g *GitOps) shallowFetchArgs(repoPath string) []string {
+	if shallow, _ := g.git(repoPath, "rev-parse", "--is-shallow-repository"); shallow != "true" {
+		return nil
+	}
+
+	if depth, err := g.git(repoPath, "config", "--get", "baseline.depth"); err == nil && depth != "" {
+		return []string{"--depth", depth}
+	}
+	if since, err := g.git(repoPath, "config", "--get", "baseline.shallowSince"); err == nil && since != "" {
+		return []string{"--shallow-since=" + since}
+	}
+
+	// Fetching without depth keeps the existing shallow boundary
+	return nil
+}
@@ -0,0 +1,47 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+func TestParseStrategyRule(t *testing.T) {
+	rule, err := ParseStrategyRule("acme/mono*:depth=1,filter=blob:none")
+	if err != nil {
+		t.Fatalf("ParseStrategyRule failed: %v", err)
+	}
+
+	expected := StrategyRule{Pattern: "acme/mono*", Strategy: Strategy{Depth: 1, Filter: "blob:none"}}
+	if rule != expected {
+		t.Errorf("Expected %+v, got %+v", expected, rule)
+	}
+
+	for _, spec := range []string{"acme/api", "acme/api:depth=x", "acme/api:depth=1,shallow-since=2024-01-01", "acme/api:filter=sparse:oid=x", "acme/api:bare"} {
+		if _, err := ParseStrategyRule(spec); err == nil {
+			t.Errorf("Expected ParseStrategyRule(%q) to fail", spec)
+		}
+	}
+}
+
+func TestStrategyFor(t *testing.T) {
+	gitOps := NewGitOpsWithOptions(false, Options{
+		Strategy: Strategy{Filter: "tree:0"},
+		StrategyRules: []StrategyRule{
+			{Pattern: "acme/monorepo", Strategy: Strategy{Depth: 1}},
+			{Pattern: "acme/*", Strategy: Strategy{}},
+		},
+	})
+
+	cases := map[string]Strategy{
+		"acme/monorepo": {Depth: 1},
+		"acme/api":      {},
+		"other/api":     {Filter: "tree:0"},
+	}
+
+	for name, expected := range cases {
+		if strategy := gitOps.strategyFor(types.Repository{FullName: name}); strategy != expected {
+			t.Errorf("Expected strategy %+v for %s, got %+v", expected, name, strategy)
+		}
+	}
+}
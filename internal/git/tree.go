@@ -0,0 +1,135 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ResolveCommit returns the commit hash rev points to in the repository at repoPath
+func ResolveCommit(repoPath, rev string) (string, error) {
+	output, err := exec.Command("git", "-C", repoPath, "rev-parse", "--verify", "-q", rev+"^{commit}").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s has no commit %s", repoPath, rev)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ListWorkingTree returns the tracked and the untracked, not ignored files of a checkout
+func ListWorkingTree(repoPath string) ([]string, error) {
+	return listFiles(repoPath, "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+}
+
+// ListTree returns the files in the tree of rev
+func ListTree(repoPath, rev string) ([]string, error) {
+	return listFiles(repoPath, "ls-tree", "-r", "-z", "--name-only", rev)
+}
+
+// ChangedFiles returns the files added, modified or deleted between two commits
+func ChangedFiles(repoPath, from, to string) ([]string, error) {
+	return listFiles(repoPath, "diff", "--name-only", "-z", "--no-renames", from, to)
+}
+
+// listFiles runs a Git command listing NUL separated file names
+func listFiles(repoPath string, args ...string) ([]string, error) {
+	cmd := exec.Command("git", append([]string{"-C", repoPath}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s in %s: %w: %s", args[0], repoPath, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var names []string
+	for _, name := range strings.Split(string(output), "\x00") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// ReadBlobs reads the files of rev through a single git cat-file process and calls fn
+// with the content of each. Names that are missing or not blobs, such as submodules,
+// are skipped, as are names with a newline or NUL byte, which cannot be requested.
+// Cancelling the context stops git.
+func ReadBlobs(ctx context.Context, repoPath, rev string, names []string, fn func(name string, content []byte)) error {
+	// cat-file reads one object name per line, a newline in a name would request a
+	// second object that is never read, leaving git blocked on a full pipe
+	var requested []string
+	var input bytes.Buffer
+	for _, name := range names {
+		if strings.ContainsAny(name, "\n\x00") {
+			continue
+		}
+		requested = append(requested, name)
+		input.WriteString(rev + ":" + name + "\n")
+	}
+	if len(requested) == 0 {
+		return nil
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "-C", repoPath, "cat-file", "--batch")
+	cmd.Stdin = &input
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", repoPath, err)
+	}
+
+	reader := bufio.NewReader(stdout)
+	for _, name := range requested {
+		content, err := readBlob(reader)
+		if err != nil {
+			// Let git finish writing before waiting for it
+			_, _ = io.Copy(io.Discard, reader)
+			_ = cmd.Wait()
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if content != nil {
+			fn(name, content)
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", repoPath, err)
+	}
+	return nil
+}
+
+// readBlob reads the next object from git cat-file --batch, returning nil
+// content for objects that are missing or not blobs
+func readBlob(r *bufio.Reader) ([]byte, error) {
+	header, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		// "<name> missing" has no content
+		return nil, nil
+	}
+
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected object header %q", strings.TrimSpace(header))
+	}
+
+	content := make([]byte, size+1)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+
+	if fields[1] != "blob" {
+		return nil, nil
+	}
+	return content[:size], nil
+}
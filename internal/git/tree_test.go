@@ -0,0 +1,28 @@
+package git
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jonasbn/baseline/internal/testutil"
+)
+
+func TestReadBlobsSkipsNewlineNames(t *testing.T) {
+	_, work := setupUpstream(t, t.TempDir())
+	testutil.CommitFiles(t, work, map[string]string{"a\nHEAD:README.md": "newline"})
+
+	read := make(map[string]string)
+	names := []string{"a\nHEAD:README.md", "missing.txt", "README.md"}
+	err := ReadBlobs(context.Background(), work, "HEAD", names, func(name string, content []byte) {
+		read[name] = string(content)
+	})
+	if err != nil {
+		t.Fatalf("ReadBlobs failed: %v", err)
+	}
+
+	expected := map[string]string{"README.md": "one"}
+	if !reflect.DeepEqual(read, expected) {
+		t.Errorf("Expected %v, got %v", expected, read)
+	}
+}
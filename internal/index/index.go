@@ -0,0 +1,337 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/jonasbn/baseline/internal/git"
+	"github.com/jonasbn/baseline/internal/store"
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// Dir is the directory below the metadata directory of the baseline holding the indexes
+const Dir = "index"
+
+// Store keeps the index of each repository of the baseline
+var Store = store.Store{Dir: Dir, Ext: ".idx", Name: "index"}
+
+// magic starts every index file, its last byte is the format version, increased whenever
+// the stored format changes; indexes in another format are rebuilt
+var magic = []byte("BLINDEX\x02")
+
+// binaryProbe is the number of leading bytes inspected for NUL bytes, as Git does
+const binaryProbe = 8000
+
+// lfsPointer starts the pointer files Git LFS stores in place of the content
+var lfsPointer = []byte("version https://git-lfs.github.com/spec/v1")
+
+// Index is the inverted trigram index of the files in the tree of a commit: for each
+// trigram the files containing it. Trigrams are taken from the content with ASCII
+// letters lower-cased, so one index serves case-sensitive and case-insensitive queries.
+//
+// On disk the directory below is followed by the posting lists, the file IDs of each
+// trigram as delta-encoded varints. Load reads the directory only, queries read the
+// posting lists of their trigrams.
+type Index struct {
+	Commit string
+	// Files are the indexed files, sorted; a file is identified by its position
+	Files []string
+	// Trigrams are the sorted trigrams found, Offsets[i] to Offsets[i+1] delimits the
+	// posting list of Trigrams[i]
+	Trigrams []uint32
+	Offsets  []int64
+
+	// path is the index file and postings the offset of the posting lists in it
+	path     string
+	postings int64
+}
+
+// Result is the outcome of indexing a repository
+type Result struct {
+	Repository types.Repository
+	// Rebuilt is set when the index was built from scratch instead of updated
+	Rebuilt bool
+	// Changed is the number of files indexed again
+	Changed  int
+	Error    error
+	Duration time.Duration
+}
+
+// Load reads the directory of the index of the repository stored at repoPath. A missing
+// index, or one in another format, is reported as os.ErrNotExist.
+func Load(root, repoPath string) (*Index, error) {
+	path, err := Store.Path(root, repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header := make([]byte, len(magic)+8)
+	if _, err := io.ReadFull(f, header); err != nil || !bytes.Equal(header[:len(magic)], magic) {
+		return nil, os.ErrNotExist
+	}
+	size := int64(binary.BigEndian.Uint64(header[len(magic):]))
+
+	idx := &Index{path: path, postings: int64(len(header)) + size}
+	if err := gob.NewDecoder(io.LimitReader(f, size)).Decode(idx); err != nil {
+		return nil, fmt.Errorf("failed to read index %s: %w", path, err)
+	}
+	if len(idx.Offsets) != len(idx.Trigrams)+1 {
+		return nil, fmt.Errorf("failed to read index %s: inconsistent directory", path)
+	}
+
+	return idx, nil
+}
+
+// table holds the trigrams of each file of a commit while the index is updated
+type table struct {
+	commit string
+	files  map[string][]uint32
+	// idx is the stored index the files are read from when first needed
+	idx *Index
+}
+
+func (t *table) Commit() string {
+	return t.commit
+}
+
+func (t *table) Update(ctx context.Context, repoPath, commit string, names []string) error {
+	if t.files == nil {
+		files, err := t.idx.forward()
+		if err != nil {
+			return err
+		}
+		t.files = files
+	}
+
+	// Deleted files are missing from the tree and stay removed
+	for _, name := range names {
+		delete(t.files, name)
+	}
+	t.commit = commit
+	return git.ReadBlobs(ctx, repoPath, commit, names, func(name string, content []byte) {
+		// The checkout holds the LFS content the pointer stands for, files left out of
+		// the index are always searched
+		if bytes.HasPrefix(content, lfsPointer) {
+			return
+		}
+		t.files[name] = Trigrams(content)
+	})
+}
+
+// Encode returns the index of the files: the header, the directory and the posting lists
+func (t *table) Encode() ([]byte, error) {
+	idx := &Index{Commit: t.commit, Files: make([]string, 0, len(t.files))}
+	for name := range t.files {
+		idx.Files = append(idx.Files, name)
+	}
+	sort.Strings(idx.Files)
+
+	// Files are added in order, so every posting list is sorted
+	lists := make(map[uint32][]uint32)
+	for id, name := range idx.Files {
+		for _, tri := range t.files[name] {
+			lists[tri] = append(lists[tri], uint32(id))
+		}
+	}
+	for tri := range lists {
+		idx.Trigrams = append(idx.Trigrams, tri)
+	}
+	sort.Slice(idx.Trigrams, func(i, j int) bool { return idx.Trigrams[i] < idx.Trigrams[j] })
+
+	var postings []byte
+	idx.Offsets = make([]int64, 0, len(idx.Trigrams)+1)
+	for _, tri := range idx.Trigrams {
+		idx.Offsets = append(idx.Offsets, int64(len(postings)))
+		prev := uint32(0)
+		for _, id := range lists[tri] {
+			postings = binary.AppendUvarint(postings, uint64(id-prev))
+			prev = id
+		}
+	}
+	idx.Offsets = append(idx.Offsets, int64(len(postings)))
+
+	var directory bytes.Buffer
+	if err := gob.NewEncoder(&directory).Encode(idx); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(magic)
+	buf.Write(binary.BigEndian.AppendUint64(nil, uint64(directory.Len())))
+	buf.Write(directory.Bytes())
+	buf.Write(postings)
+
+	return buf.Bytes(), nil
+}
+
+// postingReader reads posting lists from an index file
+type postingReader struct {
+	idx *Index
+	f   *os.File
+}
+
+// list returns the sorted IDs of the files containing the trigram
+func (r *postingReader) list(t uint32) ([]uint32, error) {
+	i := sort.Search(len(r.idx.Trigrams), func(i int) bool { return r.idx.Trigrams[i] >= t })
+	if i == len(r.idx.Trigrams) || r.idx.Trigrams[i] != t {
+		return nil, nil
+	}
+
+	data := make([]byte, r.idx.Offsets[i+1]-r.idx.Offsets[i])
+	if _, err := r.f.ReadAt(data, r.idx.postings+r.idx.Offsets[i]); err != nil {
+		return nil, fmt.Errorf("failed to read index %s: %w", r.idx.path, err)
+	}
+
+	var ids []uint32
+	prev := uint32(0)
+	for len(data) > 0 {
+		delta, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("failed to read index %s: corrupt posting list", r.idx.path)
+		}
+		prev += uint32(delta)
+		ids = append(ids, prev)
+		data = data[n:]
+	}
+	return ids, nil
+}
+
+// forward reads all posting lists and returns the trigrams of each file
+func (idx *Index) forward() (map[string][]uint32, error) {
+	f, err := os.Open(idx.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	files := make(map[string][]uint32, len(idx.Files))
+	for _, name := range idx.Files {
+		files[name] = []uint32{}
+	}
+
+	r := &postingReader{idx: idx, f: f}
+	for _, t := range idx.Trigrams {
+		ids, err := r.list(t)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			if int(id) >= len(idx.Files) {
+				return nil, fmt.Errorf("failed to read index %s: corrupt posting list", idx.path)
+			}
+			name := idx.Files[id]
+			files[name] = append(files[name], t)
+		}
+	}
+	return files, nil
+}
+
+// Refresh brings the index of the repository stored at its BaselinePath up to date with
+// its HEAD. An existing index is updated with the files changed since the indexed
+// commit, otherwise, or if that commit is no longer available, it is built from scratch.
+func Refresh(ctx context.Context, root string, repo types.Repository) Result {
+	start := time.Now()
+	result := Result{Repository: repo}
+
+	result.Rebuilt, result.Changed, result.Error = refresh(ctx, root, repo.BaselinePath)
+	result.Duration = time.Since(start)
+
+	return result
+}
+
+func refresh(ctx context.Context, root, repoPath string) (bool, int, error) {
+	load := func() (store.Table, error) {
+		idx, err := Load(root, repoPath)
+		if err != nil {
+			return nil, err
+		}
+		return &table{commit: idx.Commit, idx: idx}, nil
+	}
+	create := func() store.Table {
+		return &table{files: make(map[string][]uint32)}
+	}
+	return Store.Refresh(ctx, root, repoPath, load, create)
+}
+
+// Trigrams returns the sorted trigrams of the content, none for binary content.
+// Trigrams spanning lines are left out, searches never match across lines.
+func Trigrams(content []byte) []uint32 {
+	if bytes.IndexByte(content[:min(len(content), binaryProbe)], 0) >= 0 {
+		return []uint32{}
+	}
+
+	set := make(map[uint32]struct{})
+	for i := 0; i+2 < len(content); i++ {
+		a, b, c := lower(content[i]), lower(content[i+1]), lower(content[i+2])
+		if a == '\n' || b == '\n' || c == '\n' {
+			continue
+		}
+		set[uint32(a)<<16|uint32(b)<<8|uint32(c)] = struct{}{}
+	}
+
+	trigrams := make([]uint32, 0, len(set))
+	for t := range set {
+		trigrams = append(trigrams, t)
+	}
+	sort.Slice(trigrams, func(i, j int) bool { return trigrams[i] < trigrams[j] })
+
+	return trigrams
+}
+
+// lower lower-cases ASCII letters
+func lower(b byte) byte {
+	if 'A' <= b && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
+
+// Candidates returns the filter of the files that may match the query, reading the
+// posting lists of its trigrams. Files not in the index, such as untracked files of a
+// checkout, are always candidates.
+func (idx *Index) Candidates(q *Query) (func(name string) bool, error) {
+	if q.All() {
+		return func(string) bool { return true }, nil
+	}
+
+	f, err := os.Open(idx.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := &postingReader{idx: idx, f: f}
+	ids, all, err := q.eval(r.list)
+	if err != nil {
+		return nil, err
+	}
+	if all {
+		return func(string) bool { return true }, nil
+	}
+
+	matching := make(map[uint32]bool, len(ids))
+	for _, id := range ids {
+		matching[id] = true
+	}
+
+	return func(name string) bool {
+		i := sort.SearchStrings(idx.Files, name)
+		if i == len(idx.Files) || idx.Files[i] != name {
+			return true
+		}
+		return matching[uint32(i)]
+	}, nil
+}
@@ -0,0 +1,210 @@
+package index
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/jonasbn/baseline/internal/testutil"
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		content  string
+		expected bool
+	}{
+		{"hello", "say Hello world", true},
+		{"hello", "say goodbye", false},
+		{"(?i)HELLO", "hello", true},
+		{"foo.*bar", "foo and bar", true},
+		{"foo.*bar", "foo only", false},
+		{"(alpha|beta)Test", "betatest", true},
+		{"(alpha|beta)Test", "gammatest", false},
+		{"ab", "nothing alike", true},
+		{"x+yz", "xxyz", true},
+		{`\w+Error`, "parseError", true},
+		{`\w+Error`, "anything", false},
+		{"colou?r", "color", true},
+		{"colou?r", "colr", false},
+		{"v[0-3]\\.x", "v1.x", true},
+		{"v[0-3]\\.x", "v.x", false},
+		{"v[0-9]\\.x", "v.x", true},
+		{"^func main", "package main\nfunc main() {}", true},
+		{"func main", "func\nmain", false},
+	}
+
+	for _, tt := range tests {
+		q, err := NewQuery(tt.pattern)
+		if err != nil {
+			t.Fatalf("NewQuery(%q) failed: %v", tt.pattern, err)
+		}
+		if got := matchesContent(t, q, tt.content); got != tt.expected {
+			t.Errorf("Query %q on %q: expected %t, got %t", tt.pattern, tt.content, tt.expected, got)
+		}
+	}
+}
+
+// matchesContent evaluates the query against a single file with the content
+func matchesContent(t *testing.T, q *Query, content string) bool {
+	t.Helper()
+	trigrams := Trigrams([]byte(content))
+	ids, all, err := q.eval(func(tri uint32) ([]uint32, error) {
+		i := sort.Search(len(trigrams), func(i int) bool { return trigrams[i] >= tri })
+		if i < len(trigrams) && trigrams[i] == tri {
+			return []uint32{0}, nil
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("eval failed: %v", err)
+	}
+	return all || len(ids) == 1
+}
+
+func TestCandidates(t *testing.T) {
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "acme", "api")
+	files := map[string][]uint32{
+		"a.go":  Trigrams([]byte("func NewClient() {}\n")),
+		"b.go":  Trigrams([]byte("func NewServer() {}\n")),
+		"c.go":  Trigrams([]byte("type Client struct{}\n")),
+		"bin":   Trigrams([]byte("NewClient\x00")),
+		"empty": Trigrams(nil),
+	}
+	data, err := (&table{commit: "abc", files: files}).Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if err := Store.Write(root, repoPath, data); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	idx, err := Load(root, repoPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if idx.Commit != "abc" || len(idx.Files) != 5 {
+		t.Fatalf("Unexpected index %+v", idx)
+	}
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"NewClient", []string{"a.go", "untracked"}},
+		{"(?i)newclient|newserver", []string{"a.go", "b.go", "untracked"}},
+		{"Client", []string{"a.go", "c.go", "untracked"}},
+		{"func New(Client|Server)", []string{"a.go", "b.go", "untracked"}},
+		{"Nothing", []string{"untracked"}},
+		{".", []string{"a.go", "b.go", "bin", "c.go", "empty", "untracked"}},
+	}
+	for _, tt := range tests {
+		q, err := NewQuery(tt.pattern)
+		if err != nil {
+			t.Fatalf("NewQuery(%q) failed: %v", tt.pattern, err)
+		}
+		candidate, err := idx.Candidates(q)
+		if err != nil {
+			t.Fatalf("Candidates(%q) failed: %v", tt.pattern, err)
+		}
+		var got []string
+		for _, name := range []string{"a.go", "b.go", "bin", "c.go", "empty", "untracked"} {
+			if candidate(name) {
+				got = append(got, name)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Query %q: expected %v, got %v", tt.pattern, tt.expected, got)
+		}
+	}
+
+	// The trigrams of every file are recovered for incremental updates
+	forward, err := idx.forward()
+	if err != nil {
+		t.Fatalf("forward failed: %v", err)
+	}
+	if !reflect.DeepEqual(forward, files) {
+		t.Errorf("Expected %v, got %v", files, forward)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	testutil.SetIdentity(t)
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "acme", "api")
+	testutil.InitRepository(t, dir, "")
+	repo := types.Repository{FullName: "acme/api", BaselinePath: dir}
+
+	// An empty repository has no index
+	if result := Refresh(context.Background(), root, repo); result.Error != nil || result.Changed != 0 {
+		t.Fatalf("Unexpected result for an empty repository: %+v", result)
+	}
+
+	testutil.CommitFiles(t, dir, map[string]string{"a.txt": "alpha\n", "b.txt": "beta\n", "c.txt": "gamma\n"})
+
+	result := Refresh(context.Background(), root, repo)
+	if result.Error != nil || !result.Rebuilt || result.Changed != 3 {
+		t.Fatalf("Expected a full build of 3 files, got %+v", result)
+	}
+
+	// Unchanged repositories are not indexed again
+	if result := Refresh(context.Background(), root, repo); result.Error != nil || result.Changed != 0 {
+		t.Fatalf("Expected no change, got %+v", result)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "c.txt")); err != nil {
+		t.Fatal(err)
+	}
+	testutil.CommitFiles(t, dir, map[string]string{"a.txt": "delta\n"})
+
+	result = Refresh(context.Background(), root, repo)
+	if result.Error != nil || result.Rebuilt || result.Changed != 2 {
+		t.Fatalf("Expected an update of 2 files, got %+v", result)
+	}
+
+	idx, err := Load(root, dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !reflect.DeepEqual(idx.Files, []string{"a.txt", "b.txt"}) {
+		t.Errorf("Deleted file should be removed from the index, got %v", idx.Files)
+	}
+
+	q, _ := NewQuery("alpha")
+	if candidate, err := idx.Candidates(q); err != nil || candidate("a.txt") {
+		t.Errorf("Changed file should be indexed with its new content (%v)", err)
+	}
+	q, _ = NewQuery("delta")
+	if candidate, err := idx.Candidates(q); err != nil || !candidate("a.txt") {
+		t.Errorf("Changed file should match its new content (%v)", err)
+	}
+
+	// Git LFS pointers do not describe the content of the checkout and are not indexed
+	testutil.CommitFiles(t, dir, map[string]string{"model.bin": "version https://git-lfs.github.com/spec/v1\noid sha256:4d7a\nsize 12\n"})
+	if result := Refresh(context.Background(), root, repo); result.Error != nil {
+		t.Fatalf("Refresh failed: %v", result.Error)
+	}
+	idx, err = Load(root, dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	q, _ = NewQuery("weights")
+	if candidate, err := idx.Candidates(q); err != nil || !candidate("model.bin") || candidate("b.txt") {
+		t.Errorf("LFS pointer should always be a candidate (%v)", err)
+	}
+
+	// Moving the repository moves its index
+	newDir := filepath.Join(root, "acme", "service")
+	if err := Store.Move(root, dir, newDir); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if _, err := Load(root, newDir); err != nil {
+		t.Errorf("Expected the index at the new path: %v", err)
+	}
+}
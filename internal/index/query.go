@@ -0,0 +1,310 @@
+package index
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxExact is the largest set of exact strings tracked while analysing a regular
+// expression, larger sets are turned into trigram queries
+const maxExact = 16
+
+// maxClass is the largest character class expanded into exact strings
+const maxClass = 8
+
+// queryOp is the operation of a query
+type queryOp int
+
+const (
+	// queryAll matches every file
+	queryAll queryOp = iota
+	// queryAnd matches files with all trigrams and all sub-queries
+	queryAnd
+	// queryOr matches files with any trigram or any sub-query
+	queryOr
+)
+
+// Query is a boolean combination of trigrams a file must contain to possibly match
+// a regular expression
+type Query struct {
+	op       queryOp
+	trigrams []uint32
+	sub      []*Query
+}
+
+var allQuery = &Query{op: queryAll}
+
+// NewQuery derives the trigram query for a regular expression in Go syntax. The
+// query may accept files the expression does not match, never the other way round.
+func NewQuery(expr string) (*Query, error) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search pattern: %w", err)
+	}
+	return analyze(re.Simplify()).query(), nil
+}
+
+// All reports whether the query matches every file, so the index cannot narrow the search
+func (q *Query) All() bool {
+	return q.op == queryAll
+}
+
+// eval evaluates the query against posting lists, returning the sorted IDs of the files
+// that may match, or all when the query matches every file
+func (q *Query) eval(list func(t uint32) ([]uint32, error)) ([]uint32, bool, error) {
+	switch q.op {
+	case queryAnd:
+		var result []uint32
+		all := true
+		for _, t := range q.trigrams {
+			ids, err := list(t)
+			if err != nil {
+				return nil, false, err
+			}
+			result, all = intersect(result, all, ids), false
+		}
+		for _, sub := range q.sub {
+			ids, subAll, err := sub.eval(list)
+			if err != nil {
+				return nil, false, err
+			}
+			if !subAll {
+				result, all = intersect(result, all, ids), false
+			}
+		}
+		return result, all, nil
+	case queryOr:
+		var result []uint32
+		for _, t := range q.trigrams {
+			ids, err := list(t)
+			if err != nil {
+				return nil, false, err
+			}
+			result = union(result, ids)
+		}
+		for _, sub := range q.sub {
+			ids, subAll, err := sub.eval(list)
+			if err != nil || subAll {
+				return nil, subAll, err
+			}
+			result = union(result, ids)
+		}
+		return result, false, nil
+	default:
+		return nil, true, nil
+	}
+}
+
+// intersect returns the IDs in both sorted lists, a is every file when all is set
+func intersect(a []uint32, all bool, b []uint32) []uint32 {
+	if all {
+		return b
+	}
+	var result []uint32
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// union returns the IDs in either sorted list
+func union(a, b []uint32) []uint32 {
+	result := make([]uint32, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			result = append(result, a[i])
+			i++
+		case a[i] > b[j]:
+			result = append(result, b[j])
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	result = append(result, a[i:]...)
+	return append(result, b[j:]...)
+}
+
+// and combines two queries that must both hold
+func and(a, b *Query) *Query {
+	if a.op == queryAll {
+		return b
+	}
+	if b.op == queryAll {
+		return a
+	}
+	return &Query{op: queryAnd, sub: []*Query{a, b}}
+}
+
+// or combines two queries of which one must hold
+func or(a, b *Query) *Query {
+	if a.op == queryAll || b.op == queryAll {
+		return allQuery
+	}
+	return &Query{op: queryOr, sub: []*Query{a, b}}
+}
+
+// info describes what a regular expression matches: either a small set of exact
+// strings, or a query every match satisfies
+type info struct {
+	exact map[string]bool
+	match *Query
+}
+
+// anything is the info of an expression about which nothing is known
+var anything = info{match: allQuery}
+
+// exactInfo is the info of an expression matching exactly the strings
+func exactInfo(strings ...string) info {
+	exact := make(map[string]bool, len(strings))
+	for _, s := range strings {
+		exact[s] = true
+	}
+	return info{exact: exact}
+}
+
+// query returns the query all matches of the expression satisfy
+func (i info) query() *Query {
+	if i.exact == nil {
+		return i.match
+	}
+
+	q := &Query{op: queryOr}
+	for s := range i.exact {
+		// A string shorter than a trigram can match anywhere
+		if len(s) < 3 {
+			return allQuery
+		}
+		q.sub = append(q.sub, &Query{op: queryAnd, trigrams: stringTrigrams(s)})
+	}
+	if len(q.sub) == 1 {
+		return q.sub[0]
+	}
+	return q
+}
+
+// stringTrigrams returns the lower-cased trigrams of s
+func stringTrigrams(s string) []uint32 {
+	var trigrams []uint32
+	for i := 0; i+2 < len(s); i++ {
+		trigrams = append(trigrams, uint32(lower(s[i]))<<16|uint32(lower(s[i+1]))<<8|uint32(lower(s[i+2])))
+	}
+	return trigrams
+}
+
+// analyze computes the info of a simplified regular expression
+func analyze(re *syntax.Regexp) info {
+	switch re.Op {
+	case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine,
+		syntax.OpBeginText, syntax.OpEndText, syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return exactInfo("")
+
+	case syntax.OpLiteral:
+		s := string(re.Rune)
+		if re.Flags&syntax.FoldCase != 0 && !foldsLikeASCII(re.Rune) {
+			// The index only folds ASCII letters
+			return anything
+		}
+		return exactInfo(s)
+
+	case syntax.OpCharClass:
+		var runes []string
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			for r := re.Rune[i]; r <= re.Rune[i+1]; r++ {
+				if len(runes) == maxClass {
+					return anything
+				}
+				runes = append(runes, string(r))
+			}
+		}
+		if len(runes) == 0 {
+			return anything
+		}
+		return exactInfo(runes...)
+
+	case syntax.OpCapture:
+		return analyze(re.Sub[0])
+
+	case syntax.OpPlus:
+		return info{match: analyze(re.Sub[0]).query()}
+
+	case syntax.OpRepeat:
+		if re.Min == 0 {
+			return anything
+		}
+		return info{match: analyze(re.Sub[0]).query()}
+
+	case syntax.OpConcat:
+		result := exactInfo("")
+		for _, sub := range re.Sub {
+			result = concat(result, analyze(sub))
+		}
+		return result
+
+	case syntax.OpAlternate:
+		result := analyze(re.Sub[0])
+		for _, sub := range re.Sub[1:] {
+			result = alternate(result, analyze(sub))
+		}
+		return result
+
+	default:
+		// OpAnyChar, OpAnyCharNotNL, OpStar, OpQuest and OpNoMatch
+		return anything
+	}
+}
+
+// concat combines the info of two expressions matched one after the other
+func concat(a, b info) info {
+	if a.exact != nil && b.exact != nil && len(a.exact)*len(b.exact) <= maxExact {
+		exact := make(map[string]bool, len(a.exact)*len(b.exact))
+		for x := range a.exact {
+			for y := range b.exact {
+				exact[x+y] = true
+			}
+		}
+		return info{exact: exact}
+	}
+	return info{match: and(a.query(), b.query())}
+}
+
+// alternate combines the info of two alternative expressions
+func alternate(a, b info) info {
+	if a.exact != nil && b.exact != nil && len(a.exact)+len(b.exact) <= maxExact {
+		exact := make(map[string]bool, len(a.exact)+len(b.exact))
+		for s := range a.exact {
+			exact[s] = true
+		}
+		for s := range b.exact {
+			exact[s] = true
+		}
+		return info{exact: exact}
+	}
+	return info{match: or(a.query(), b.query())}
+}
+
+// foldsLikeASCII reports whether the runes have no case outside ASCII. The Kelvin
+// and long s signs folding to k and s are deliberately not taken into account.
+func foldsLikeASCII(runes []rune) bool {
+	for _, r := range runes {
+		if r >= utf8.RuneSelf && unicode.SimpleFold(r) != r {
+			return false
+		}
+	}
+	return true
+}
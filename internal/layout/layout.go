@@ -0,0 +1,193 @@
+package layout
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jonasbn/baseline/internal/remote"
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+const (
+	// OwnerTemplate stores repositories as owner/name, the default layout
+	OwnerTemplate = "{owner}/{name}"
+	// HostTemplate stores repositories as host/owner/name, avoiding collisions across hosts
+	HostTemplate = "{host}/{owner}/{name}"
+)
+
+// presets maps the names accepted by --layout to their templates
+var presets = map[string]string{
+	"owner": OwnerTemplate,
+	"host":  HostTemplate,
+}
+
+// placeholders lists the supported template placeholders and the pattern
+// each one matches when parsing a path; only the owner may span directories
+var placeholders = map[string]string{
+	"{host}":   `[^/]+`,
+	"{source}": `[^/]+`,
+	"{owner}":  `.+`,
+	"{name}":   `[^/]+`,
+}
+
+var placeholderPattern = regexp.MustCompile(`\{[a-z]+\}`)
+
+// Fields holds the values of the placeholders of a repository path
+type Fields struct {
+	Host   string
+	Source string
+	Owner  string
+	Name   string
+}
+
+// Layout resolves where repositories are stored below the baseline directory
+type Layout struct {
+	template string
+	pattern  *regexp.Regexp
+	groups   []string
+}
+
+// Default returns the default owner/name layout
+func Default() *Layout {
+	l, _ := New(OwnerTemplate)
+	return l
+}
+
+// Resolve returns the layout for a preset name ("owner" or "host") or a path template
+// such as "{source}/{owner}/{name}"
+func Resolve(nameOrTemplate string) (*Layout, error) {
+	if template, ok := presets[nameOrTemplate]; ok {
+		return New(template)
+	}
+
+	if !strings.Contains(nameOrTemplate, "{") {
+		return nil, fmt.Errorf("unknown layout %q (supported: owner, host or a template such as %s)", nameOrTemplate, HostTemplate)
+	}
+
+	return New(nameOrTemplate)
+}
+
+// New creates a layout from a path template using the placeholders
+// {host}, {source}, {owner} and {name}
+func New(template string) (*Layout, error) {
+	template = strings.Trim(path.Clean(filepath.ToSlash(template)), "/")
+
+	if !strings.HasSuffix(template, "{name}") {
+		return nil, fmt.Errorf("layout template %q must end with {name}", template)
+	}
+
+	var groups []string
+	var expression strings.Builder
+	expression.WriteString("^")
+
+	last := 0
+	for _, loc := range placeholderPattern.FindAllStringIndex(template, -1) {
+		placeholder := template[loc[0]:loc[1]]
+		pattern, ok := placeholders[placeholder]
+		if !ok {
+			return nil, fmt.Errorf("unknown placeholder %s in layout template %q", placeholder, template)
+		}
+
+		for _, group := range groups {
+			if group == placeholder {
+				return nil, fmt.Errorf("placeholder %s is used more than once in layout template %q", placeholder, template)
+			}
+		}
+
+		expression.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+		expression.WriteString("(" + pattern + ")")
+		groups = append(groups, placeholder)
+		last = loc[1]
+	}
+	expression.WriteString(regexp.QuoteMeta(template[last:]))
+	expression.WriteString("$")
+
+	return &Layout{
+		template: template,
+		pattern:  regexp.MustCompile(expression.String()),
+		groups:   groups,
+	}, nil
+}
+
+// String returns the path template of the layout
+func (l *Layout) String() string {
+	return l.template
+}
+
+// Path returns the directory of the repository below targetDir
+func (l *Layout) Path(repo types.Repository, targetDir string) string {
+	return filepath.Join(targetDir, filepath.FromSlash(l.RelativePath(repo)))
+}
+
+// RelativePath returns the slash-separated path of the repository relative to the baseline directory
+func (l *Layout) RelativePath(repo types.Repository) string {
+	replacer := strings.NewReplacer(
+		"{host}", sanitize(Host(repo)),
+		"{source}", sanitize(repo.Source),
+		"{owner}", sanitize(repo.Owner),
+		"{name}", sanitize(repo.Name),
+	)
+	return path.Clean(replacer.Replace(l.template))
+}
+
+// Parse extracts the placeholder values from a slash-separated path relative to the
+// baseline directory. It reports false if the path does not match the layout.
+func (l *Layout) Parse(relPath string) (Fields, bool) {
+	match := l.pattern.FindStringSubmatch(filepath.ToSlash(relPath))
+	if match == nil {
+		return Fields{}, false
+	}
+
+	var fields Fields
+	for i, group := range l.groups {
+		value := match[i+1]
+		switch group {
+		case "{host}":
+			fields.Host = value
+		case "{source}":
+			fields.Source = value
+		case "{owner}":
+			fields.Owner = value
+		case "{name}":
+			fields.Name = value
+		}
+	}
+
+	return fields, true
+}
+
+// Host returns the host name a repository is served from, derived from its URLs.
+// Repositories without a hosted URL, e.g. local paths, use the host "local".
+func Host(repo types.Repository) string {
+	for _, u := range []string{repo.HTTPSURL, repo.CloneURL, repo.SSHURL} {
+		if u == "" || u == repo.LocalPath {
+			continue
+		}
+
+		if r, err := remote.Parse(u); err == nil && r.Host != "" {
+			return strings.ToLower(r.Host)
+		}
+	}
+
+	return "local"
+}
+
+// sanitize prevents placeholder values from escaping the baseline directory
+func sanitize(value string) string {
+	var segments []string
+	for _, segment := range strings.Split(filepath.ToSlash(value), "/") {
+		switch segment {
+		case "", ".", "..":
+			continue
+		}
+		segments = append(segments, segment)
+	}
+
+	if len(segments) == 0 {
+		return "_"
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,85 @@
+package layout
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+func TestPath(t *testing.T) {
+	github := types.Repository{
+		Name:     "api",
+		Owner:    "acme",
+		Source:   "github",
+		HTTPSURL: "https://github.com/acme/api",
+		CloneURL: "git@github.com:acme/api.git",
+	}
+	bitbucket := types.Repository{
+		Name:     "api",
+		Owner:    "acme",
+		Source:   "bitbucket",
+		CloneURL: "https://jdoe@bitbucket.org/acme/api.git",
+	}
+
+	owner := Default()
+	if owner.Path(github, "/baseline") != owner.Path(bitbucket, "/baseline") {
+		t.Error("Expected the owner layout to map both repositories to the same path")
+	}
+
+	host, err := Resolve("host")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	if path := host.Path(github, "/baseline"); path != filepath.Join("/baseline", "github.com", "acme", "api") {
+		t.Errorf("Unexpected path '%s'", path)
+	}
+
+	if path := host.Path(bitbucket, "/baseline"); path != filepath.Join("/baseline", "bitbucket.org", "acme", "api") {
+		t.Errorf("Unexpected path '%s'", path)
+	}
+
+	custom, err := Resolve("repos/{source}/{owner}/{name}")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	if rel := custom.RelativePath(github); rel != "repos/github/acme/api" {
+		t.Errorf("Unexpected relative path '%s'", rel)
+	}
+}
+
+func TestPathSanitize(t *testing.T) {
+	repo := types.Repository{Name: "..", Owner: "../../etc"}
+
+	if rel := Default().RelativePath(repo); rel != "etc/_" {
+		t.Errorf("Expected path to stay inside the baseline, got '%s'", rel)
+	}
+}
+
+func TestResolveInvalid(t *testing.T) {
+	for _, template := range []string{"flat", "{owner}", "{owner}/{repo}", "{name}/{name}"} {
+		if _, err := Resolve(template); err == nil {
+			t.Errorf("Expected Resolve(%q) to fail", template)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	host, _ := Resolve("host")
+
+	fields, ok := host.Parse("gitlab.com/acme/platform/api")
+	if !ok {
+		t.Fatal("Expected path to match the host layout")
+	}
+
+	expected := Fields{Host: "gitlab.com", Owner: "acme/platform", Name: "api"}
+	if fields != expected {
+		t.Errorf("Expected %+v, got %+v", expected, fields)
+	}
+
+	if _, ok := host.Parse("api"); ok {
+		t.Error("Expected a single segment not to match the host layout")
+	}
+}
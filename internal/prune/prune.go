@@ -0,0 +1,146 @@
+package prune
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jonasbn/baseline/internal/git"
+	"github.com/jonasbn/baseline/internal/layout"
+	"github.com/jonasbn/baseline/internal/remote"
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// Action selects what happens to orphaned repositories
+type Action string
+
+const (
+	// ActionReport only lists orphaned repositories
+	ActionReport Action = "report"
+	// ActionArchive moves orphaned repositories to the archive directory
+	ActionArchive Action = "archive"
+	// ActionDelete removes orphaned repositories
+	ActionDelete Action = "delete"
+)
+
+// ParseAction validates an action given on the command line
+func ParseAction(s string) (Action, error) {
+	switch Action(s) {
+	case ActionReport, ActionArchive, ActionDelete:
+		return Action(s), nil
+	}
+	return "", fmt.Errorf("unsupported prune action %q, use report, archive or delete", s)
+}
+
+// Scope limits pruning to the part of the baseline covered by a run, so
+// repositories of other sources and organizations are never considered orphans
+type Scope struct {
+	// Sources are the sources of the run
+	Sources []string
+	// Owners are the organizations of the run, owners below them (subgroups,
+	// projects) are included
+	Owners []string
+}
+
+// Orphan is a local repository no longer returned by its source
+type Orphan struct {
+	Path         string
+	RelativePath string
+}
+
+// Find returns the repositories below root that are within scope but not part of
+// repos, the repositories returned by the sources. Layouts without the host or
+// source in the path put the repositories of several sources in the same owner
+// directory, so a repository is only an orphan if its origin remote is on a host of
+// the run; repositories without origin or on other hosts are left alone.
+func Find(root string, l *layout.Layout, repos []types.Repository, scope Scope) ([]Orphan, error) {
+	expected := make(map[string]bool, len(repos))
+	containers := make(map[string]bool)
+	hosts := make(map[string]bool)
+	sources := make(map[string]bool)
+	for _, repo := range repos {
+		rel := l.RelativePath(repo)
+		expected[rel] = true
+		containers[path.Dir(rel)] = true
+		hosts[strings.ToLower(layout.Host(repo))] = true
+		sources[repo.Source] = true
+	}
+	for _, source := range scope.Sources {
+		sources[source] = true
+	}
+
+	paths, err := git.FindRepositories(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+
+	var orphans []Orphan
+	for _, repoPath := range paths {
+		rel, err := filepath.Rel(root, repoPath)
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if expected[rel] {
+			continue
+		}
+
+		fields, ok := l.Parse(rel)
+		if !ok || !inScope(rel, fields, containers, hosts, sources, scope.Owners) {
+			continue
+		}
+		if host, ok := originHost(repoPath); !ok || !hosts[host] {
+			continue
+		}
+
+		orphans = append(orphans, Orphan{Path: repoPath, RelativePath: rel})
+	}
+
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].RelativePath < orphans[j].RelativePath })
+	return orphans, nil
+}
+
+// inScope reports whether the local repository at rel belongs to the sources and
+// organizations of the run
+func inScope(rel string, fields layout.Fields, containers, hosts, sources map[string]bool, owners []string) bool {
+	if fields.Source != "" && !sources[fields.Source] {
+		return false
+	}
+	if fields.Host != "" && !hosts[strings.ToLower(fields.Host)] {
+		return false
+	}
+
+	// Next to repositories that still exist
+	if containers[path.Dir(rel)] {
+		return true
+	}
+
+	// Below an organization of the run, e.g. in a deleted subgroup or project
+	owner := strings.ToLower(fields.Owner)
+	for _, o := range owners {
+		o = strings.ToLower(o)
+		if owner == o || strings.HasPrefix(owner, o+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// originHost returns the lower-cased host of the origin remote of the repository at
+// repoPath, it reports false for repositories without origin or with a local origin
+func originHost(repoPath string) (string, bool) {
+	originURL, err := git.RemoteURL(repoPath, "origin")
+	if err != nil {
+		return "", false
+	}
+
+	r, err := remote.Parse(originURL)
+	if err != nil || r.Host == "" {
+		return "", false
+	}
+	return strings.ToLower(r.Host), true
+}
@@ -0,0 +1,97 @@
+package prune
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jonasbn/baseline/internal/layout"
+	"github.com/jonasbn/baseline/internal/testutil"
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// createClones creates a repository below root for each relative path, with the
+// origin remote given for it
+func createClones(t *testing.T, root string, origins map[string]string) {
+	t.Helper()
+	for rel, origin := range origins {
+		testutil.InitRepository(t, filepath.Join(root, filepath.FromSlash(rel)), origin)
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	createClones(t, root, map[string]string{
+		"acme/api":                           "https://github.com/acme/api.git",
+		"acme/old":                           "https://github.com/acme/old.git",
+		"acme/deleted-group/tool":            "git@github.com:acme/deleted-group/tool.git",
+		"jdoe/tool":                          "https://github.com/jdoe/tool.git",
+		"_archive/20250101-000000/acme/gone": "https://github.com/acme/gone.git",
+	})
+
+	repos := []types.Repository{{Name: "api", Owner: "acme", FullName: "acme/api", HTTPSURL: "https://github.com/acme/api", Source: "github"}}
+	orphans, err := Find(root, layout.Default(), repos, Scope{Sources: []string{"github"}, Owners: []string{"acme"}})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+
+	expected := []string{"acme/deleted-group/tool", "acme/old"}
+	if len(orphans) != len(expected) {
+		t.Fatalf("Expected orphans %v, got %+v", expected, orphans)
+	}
+	for i, orphan := range orphans {
+		if orphan.RelativePath != expected[i] || orphan.Path != filepath.Join(root, expected[i]) {
+			t.Errorf("Expected orphan '%s', got %+v", expected[i], orphan)
+		}
+	}
+}
+
+func TestFindHostScope(t *testing.T) {
+	root := t.TempDir()
+	createClones(t, root, map[string]string{
+		"github.com/acme/api": "https://github.com/acme/api.git",
+		"github.com/acme/old": "https://github.com/acme/old.git",
+		"gitlab.com/acme/old": "https://gitlab.com/acme/old.git",
+	})
+
+	host, _ := layout.Resolve("host")
+	repos := []types.Repository{{Name: "api", Owner: "acme", HTTPSURL: "https://github.com/acme/api", Source: "github"}}
+	orphans, err := Find(root, host, repos, Scope{Sources: []string{"github"}, Owners: []string{"acme"}})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+
+	if len(orphans) != 1 || orphans[0].RelativePath != "github.com/acme/old" {
+		t.Errorf("Expected only the orphan on the host of the run, got %+v", orphans)
+	}
+}
+
+func TestFindSharedOwner(t *testing.T) {
+	root := t.TempDir()
+	createClones(t, root, map[string]string{
+		"acme/api":     "https://github.com/acme/api.git",
+		"acme/old":     "https://github.com/acme/old.git",
+		"acme/infra":   "https://gitlab.com/acme/infra.git",
+		"acme/adopted": "/home/jdoe/src/adopted",
+		"acme/scratch": "",
+	})
+
+	// The GitLab group and the GitHub organization share the acme directory
+	repos := []types.Repository{{Name: "api", Owner: "acme", FullName: "acme/api", HTTPSURL: "https://github.com/acme/api", Source: "github"}}
+	orphans, err := Find(root, layout.Default(), repos, Scope{Sources: []string{"github"}, Owners: []string{"acme"}})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+
+	if len(orphans) != 1 || orphans[0].RelativePath != "acme/old" {
+		t.Errorf("Expected only the orphan with an origin on the host of the run, got %+v", orphans)
+	}
+}
+
+func TestParseAction(t *testing.T) {
+	if action, err := ParseAction("archive"); err != nil || action != ActionArchive {
+		t.Errorf("Expected archive, got '%s' (%v)", action, err)
+	}
+	if _, err := ParseAction("move"); err == nil {
+		t.Error("Expected ParseAction to fail for an unknown action")
+	}
+}
@@ -0,0 +1,74 @@
+package remote
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Remote represents the components of a Git remote URL
+type Remote struct {
+	Host  string // host name without port or user, empty for local paths
+	Path  string // repository path without leading slash or .git suffix
+	Owner string // all path segments but the last, e.g. "group/subgroup"
+	Name  string // last path segment
+}
+
+// Parse splits a Git remote URL into its components. It understands
+// URLs with a scheme (https://, ssh://, git://, file://), scp-like
+// SSH addresses (git@host:owner/name.git) and plain local paths.
+func Parse(rawURL string) (Remote, error) {
+	var r Remote
+	var repoPath string
+
+	switch {
+	case strings.Contains(rawURL, "://"):
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return r, fmt.Errorf("invalid remote URL %q: %w", rawURL, err)
+		}
+		r.Host = u.Hostname()
+		repoPath = u.Path
+	case isSCPLike(rawURL):
+		hostPart, pathPart, _ := strings.Cut(rawURL, ":")
+		if _, host, ok := strings.Cut(hostPart, "@"); ok {
+			hostPart = host
+		}
+		r.Host = hostPart
+		repoPath = pathPart
+	default:
+		repoPath = rawURL
+	}
+
+	repoPath = strings.Trim(path.Clean("/"+strings.ReplaceAll(repoPath, "\\", "/")), "/")
+	repoPath = strings.TrimSuffix(repoPath, ".git")
+	if repoPath == "" || repoPath == "." {
+		return r, fmt.Errorf("remote URL %q has no repository path", rawURL)
+	}
+
+	r.Path = repoPath
+	r.Name = path.Base(repoPath)
+	if dir := path.Dir(repoPath); dir != "." {
+		r.Owner = dir
+	}
+
+	return r, nil
+}
+
+// isSCPLike reports whether rawURL uses the scp-like syntax [user@]host:path.
+// Following Git, a colon only denotes a host if no slash precedes it.
+func isSCPLike(rawURL string) bool {
+	colon := strings.Index(rawURL, ":")
+	if colon <= 0 {
+		return false
+	}
+
+	slash := strings.Index(rawURL, "/")
+	if slash >= 0 && slash < colon {
+		return false
+	}
+
+	// Single letter hosts are Windows drive letters, e.g. C:\src\repo
+	return colon > 1
+}
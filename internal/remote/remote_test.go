@@ -0,0 +1,32 @@
+package remote
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected Remote
+	}{
+		{"https://github.com/acme/api.git", Remote{Host: "github.com", Path: "acme/api", Owner: "acme", Name: "api"}},
+		{"https://gitlab.com/acme/platform/api", Remote{Host: "gitlab.com", Path: "acme/platform/api", Owner: "acme/platform", Name: "api"}},
+		{"ssh://git@bitbucket.example.com:7999/plat/api.git", Remote{Host: "bitbucket.example.com", Path: "plat/api", Owner: "plat", Name: "api"}},
+		{"git@github.com:acme/api.git", Remote{Host: "github.com", Path: "acme/api", Owner: "acme", Name: "api"}},
+		{"/home/jdoe/src/api", Remote{Path: "home/jdoe/src/api", Owner: "home/jdoe/src", Name: "api"}},
+		{"./relative/path:with-colon", Remote{Path: "relative/path:with-colon", Owner: "relative", Name: "path:with-colon"}},
+	}
+
+	for _, test := range tests {
+		actual, err := Parse(test.url)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", test.url, err)
+			continue
+		}
+		if actual != test.expected {
+			t.Errorf("Parse(%q): expected %+v, got %+v", test.url, test.expected, actual)
+		}
+	}
+
+	if _, err := Parse("https://github.com/"); err == nil {
+		t.Error("Parse should fail for URLs without a repository path")
+	}
+}
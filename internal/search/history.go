@@ -0,0 +1,272 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp/syntax"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// historyBatch is the number of revisions searched by one git grep
+const historyBatch = 64
+
+// Revision is a commit a match was found in
+type Revision struct {
+	Commit string
+	Date   time.Time
+}
+
+// Hit is a line found in one or more revisions of a repository. Hits with the same
+// path and text are reported once, with the oldest and newest revision containing them.
+type Hit struct {
+	Path string
+	// Line is the line number in the newest revision
+	Line int
+	Text string
+	// First and Last are the oldest and newest revisions containing the line, by commit date
+	First     Revision
+	Last      Revision
+	Revisions int
+
+	// seen is the last revision counted, git grep reports the revisions one after another
+	seen string
+}
+
+// searchHistory searches the revisions of the repository with git grep
+func (s *Searcher) searchHistory(ctx context.Context, repoPath string) ([]Hit, error) {
+	revisions, err := s.revisions(ctx, repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make(map[string]*Hit)
+	for start := 0; start < len(revisions) && !s.Exhausted(); start += historyBatch {
+		batch := revisions[start:min(start+historyBatch, len(revisions))]
+		if err := s.grep(ctx, repoPath, batch, hits); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]Hit, 0, len(hits))
+	for _, hit := range hits {
+		result = append(result, *hit)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Path != result[j].Path {
+			return result[i].Path < result[j].Path
+		}
+		if !result[i].First.Date.Equal(result[j].First.Date) {
+			return result[i].First.Date.Before(result[j].First.Date)
+		}
+		return result[i].Text < result[j].Text
+	})
+
+	return result, nil
+}
+
+// revisions returns the commits to search: the tips of all branches and tags, or with
+// a since date every commit reachable from them made after that date
+func (s *Searcher) revisions(ctx context.Context, repoPath string) ([]Revision, error) {
+	args := []string{"-C", repoPath, "log", "--all", "--format=%H %ct"}
+	if s.since != "" {
+		args = append(args, "--since="+s.since)
+	} else {
+		args = append(args, "--no-walk")
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions of %s: %w: %s", repoPath, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var revisions []Revision
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		commit, timestamp, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected revision %q", line)
+		}
+		revisions = append(revisions, Revision{Commit: commit, Date: time.Unix(seconds, 0).UTC()})
+	}
+
+	return revisions, nil
+}
+
+// grep runs git grep over the revisions and adds the matches to hits, it stops git grep
+// when MaxMatches is reached
+func (s *Searcher) grep(ctx context.Context, repoPath string, revisions []Revision, hits map[string]*Hit) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	args := append([]string{"-C", repoPath, "grep", "-n", "-z", "-I"}, s.grepPattern()...)
+
+	dates := make(map[string]time.Time, len(revisions))
+	for _, r := range revisions {
+		args = append(args, r.Commit)
+		dates[r.Commit] = r.Date
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to search %s: %w", repoPath, err)
+	}
+
+	// Each match is printed as <commit>:<path> NUL <line> NUL <text>
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		commit, path, ok := strings.Cut(fields[0], ":")
+		if !ok || !s.selects(path) {
+			continue
+		}
+		line, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+
+		text := strings.TrimSuffix(fields[2], "\r")
+		if !s.re.MatchString(text) {
+			continue
+		}
+		revision := Revision{Commit: commit, Date: dates[commit]}
+		key := path + "\x00" + text
+
+		hit, ok := hits[key]
+		if !ok {
+			if !s.reserve() {
+				break
+			}
+			hits[key] = &Hit{Path: path, Line: line, Text: text, First: revision, Last: revision, Revisions: 1, seen: commit}
+			continue
+		}
+		// A line repeated in a file is counted once per revision
+		if hit.seen != commit {
+			hit.Revisions++
+			hit.seen = commit
+		}
+		if revision.Date.Before(hit.First.Date) {
+			hit.First = revision
+		}
+		if !revision.Date.Before(hit.Last.Date) {
+			hit.Last = revision
+			hit.Line = line
+		}
+	}
+	scanErr := scanner.Err()
+	if s.Exhausted() {
+		// Enough was found, git grep is killed instead of read to the end
+		cancel()
+		_ = cmd.Wait()
+		return nil
+	}
+	if scanErr != nil {
+		// Let git grep finish writing before waiting for it
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+
+	// git grep exits with 1 when nothing matched
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 || stderr.Len() > 0 {
+			return fmt.Errorf("git grep in %s: %w: %s", repoPath, err, strings.TrimSpace(stderr.String()))
+		}
+	}
+
+	return scanErr
+}
+
+// grepPattern returns the git grep options selecting the lines that may match. The
+// regular expressions of git grep differ from those of Go, so git grep only looks for a
+// literal the pattern requires, or prints every line, and the lines are matched with
+// the compiled pattern.
+func (s *Searcher) grepPattern() []string {
+	re, err := syntax.Parse(s.re.String(), syntax.Perl)
+	if err != nil {
+		return []string{"-e", ""}
+	}
+
+	literal, fold := requiredLiteral(re.Simplify())
+	if literal == "" {
+		return []string{"-e", ""}
+	}
+	if fold {
+		return []string{"-F", "-i", "-e", literal}
+	}
+	return []string{"-F", "-e", literal}
+}
+
+// requiredLiteral returns the longest literal every match of the expression contains,
+// and whether it is matched ignoring case
+func requiredLiteral(re *syntax.Regexp) (string, bool) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		literal := string(re.Rune)
+		// git grep reads a newline as the separator of two patterns
+		if strings.Contains(literal, "\n") {
+			return "", false
+		}
+		if re.Flags&syntax.FoldCase != 0 {
+			return foldSafe(literal), true
+		}
+		return literal, false
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredLiteral(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min > 0 {
+			return requiredLiteral(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		var longest string
+		var longestFold bool
+		for _, sub := range re.Sub {
+			if literal, fold := requiredLiteral(sub); len(literal) > len(longest) {
+				longest, longestFold = literal, fold
+			}
+		}
+		return longest, longestFold
+	}
+	return "", false
+}
+
+// foldSafe returns the longest part of a literal that git grep -i folds like Go does:
+// ASCII without k and s, which Go also folds to the Kelvin and long s signs
+func foldSafe(literal string) string {
+	var longest string
+	parts := strings.FieldsFunc(literal, func(r rune) bool {
+		return r >= utf8.RuneSelf || strings.ContainsRune("kKsS", r)
+	})
+	for _, part := range parts {
+		if len(part) > len(longest) {
+			longest = part
+		}
+	}
+	return longest
+}
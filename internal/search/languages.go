@@ -0,0 +1,49 @@
+package search
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Languages maps the file types accepted by Options.Types to file extensions
+var Languages = map[string][]string{
+	"c":          {".c", ".h"},
+	"cpp":        {".cc", ".cpp", ".cxx", ".hh", ".hpp", ".hxx", ".h"},
+	"csharp":     {".cs"},
+	"css":        {".css", ".scss", ".sass", ".less"},
+	"go":         {".go"},
+	"html":       {".html", ".htm"},
+	"java":       {".java"},
+	"javascript": {".js", ".jsx", ".mjs", ".cjs"},
+	"json":       {".json"},
+	"kotlin":     {".kt", ".kts"},
+	"markdown":   {".md", ".markdown"},
+	"perl":       {".pl", ".pm", ".t"},
+	"php":        {".php"},
+	"python":     {".py", ".pyi"},
+	"ruby":       {".rb"},
+	"rust":       {".rs"},
+	"scala":      {".scala"},
+	"shell":      {".sh", ".bash", ".zsh"},
+	"sql":        {".sql"},
+	"swift":      {".swift"},
+	"terraform":  {".tf", ".tfvars"},
+	"typescript": {".ts", ".tsx", ".mts", ".cts"},
+	"yaml":       {".yml", ".yaml"},
+}
+
+// LanguageNames returns the names of the known file types, sorted
+func LanguageNames() []string {
+	names := make([]string, 0, len(Languages))
+	for name := range Languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Extension returns the lower-case extension of a file name, including the dot
+func Extension(name string) string {
+	return strings.ToLower(path.Ext(name))
+}
@@ -0,0 +1,377 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jonasbn/baseline/internal/git"
+	"github.com/jonasbn/baseline/internal/index"
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// binaryProbe is the number of leading bytes inspected for NUL bytes, as Git does
+const binaryProbe = 8000
+
+// Options describes a search query
+type Options struct {
+	// Pattern is a regular expression, or a literal string with Literal
+	Pattern    string
+	Literal    bool
+	IgnoreCase bool
+	// Context is the number of lines shown before and after each match
+	Context int
+	// Paths limits the search to files matching one of the globs, matched against
+	// the path in the repository if the glob contains a slash and the file name otherwise
+	Paths []string
+	// Types limits the search to files of the languages, see Languages
+	Types []string
+	// IndexRoot is the baseline directory holding the indexes to narrow the search with,
+	// no index is used if empty
+	IndexRoot string
+	// History searches the tips of all branches and tags with git grep instead of the
+	// checked out files
+	History bool
+	// Since searches every commit made after the date instead of the tips, implies History
+	Since string
+	// MaxMatches stops the search once that many matching lines were found across all
+	// repositories searched with the searcher, 0 searches without limit
+	MaxMatches int
+}
+
+// Line is a line of a file, either matching or shown as context
+type Line struct {
+	Number int
+	Text   string
+	Match  bool
+}
+
+// File holds the matching and context lines of a file, in order
+type File struct {
+	Path  string
+	Lines []Line
+}
+
+// Result is the outcome of searching a repository
+type Result struct {
+	Repository types.Repository
+	Files      []File
+	// Hits are the lines found by a history search
+	Hits  []Hit
+	Error error
+	// Indexed is set when the index narrowed down the files searched
+	Indexed  bool
+	Duration time.Duration
+}
+
+// Matches returns the number of matching lines
+func (r Result) Matches() int {
+	var n int
+	for _, f := range r.Files {
+		for _, l := range f.Lines {
+			if l.Match {
+				n++
+			}
+		}
+	}
+	return n + len(r.Hits)
+}
+
+// Searcher searches the files of repositories for a pattern
+type Searcher struct {
+	re         *regexp.Regexp
+	context    int
+	paths      []string
+	extensions map[string]bool
+	indexRoot  string
+	query      *index.Query
+
+	history bool
+	since   string
+
+	// maxMatches and matches bound the lines found by all searches sharing the searcher
+	maxMatches int64
+	matches    atomic.Int64
+}
+
+// New creates a searcher for the query
+func New(opts Options) (*Searcher, error) {
+	if opts.Pattern == "" {
+		return nil, fmt.Errorf("empty search pattern")
+	}
+	if opts.Context < 0 {
+		return nil, fmt.Errorf("invalid context %d, must not be negative", opts.Context)
+	}
+
+	expr := opts.Pattern
+	if opts.Literal {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if opts.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search pattern: %w", err)
+	}
+
+	for _, glob := range opts.Paths {
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid path glob %s: %w", glob, err)
+		}
+	}
+
+	history := opts.History || opts.Since != ""
+	if history && opts.Context > 0 {
+		return nil, fmt.Errorf("context lines are not supported when searching history")
+	}
+	if history && opts.IndexRoot != "" {
+		return nil, fmt.Errorf("the index cannot be used when searching history")
+	}
+
+	s := &Searcher{
+		re:         re,
+		context:    opts.Context,
+		paths:      opts.Paths,
+		indexRoot:  opts.IndexRoot,
+		history:    history,
+		since:      opts.Since,
+		maxMatches: int64(opts.MaxMatches),
+	}
+
+	if opts.IndexRoot != "" {
+		if s.query, err = index.NewQuery(expr); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(opts.Types) > 0 {
+		s.extensions = make(map[string]bool)
+		for _, t := range opts.Types {
+			extensions, ok := Languages[strings.ToLower(t)]
+			if !ok {
+				return nil, fmt.Errorf("unknown file type %s, use one of %s", t, strings.Join(LanguageNames(), ", "))
+			}
+			for _, ext := range extensions {
+				s.extensions[ext] = true
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// Search searches a repository stored at its BaselinePath. Checkouts are searched in the
+// working tree, honouring .gitignore, mirrors in the tree of HEAD. With an index root
+// the files are narrowed down with the index of the repository, if it is up to date.
+// A history search looks at the revisions of the repository instead. Cancelling the
+// context stops the search and the Git processes it runs.
+func (s *Searcher) Search(ctx context.Context, repo types.Repository) Result {
+	start := time.Now()
+	result := Result{Repository: repo}
+
+	if s.history {
+		result.Hits, result.Error = s.searchHistory(ctx, repo.BaselinePath)
+	} else {
+		result.Files, result.Indexed, result.Error = s.search(ctx, repo.BaselinePath)
+	}
+	result.Duration = time.Since(start)
+
+	return result
+}
+
+func (s *Searcher) search(ctx context.Context, repoPath string) ([]File, bool, error) {
+	mode, err := git.DetectMode(repoPath)
+	if err != nil {
+		return nil, false, err
+	}
+
+	head, err := git.ResolveCommit(repoPath, "HEAD")
+	if err != nil && mode == git.ModeMirror {
+		// An empty repository has no HEAD to search
+		return nil, false, nil
+	}
+
+	candidate, indexed := s.candidates(repoPath, head)
+
+	var names []string
+	if mode == git.ModeMirror {
+		names, err = git.ListTree(repoPath, head)
+	} else {
+		names, err = git.ListWorkingTree(repoPath)
+		// Untracked files are listed after the tracked ones
+		sort.Strings(names)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var selected []string
+	for _, name := range names {
+		if s.selects(name) && candidate(name) {
+			selected = append(selected, name)
+		}
+	}
+
+	if mode == git.ModeMirror {
+		files, err := s.searchTree(ctx, repoPath, head, selected)
+		return files, indexed, err
+	}
+	files, err := s.searchWorkingTree(ctx, repoPath, selected)
+	return files, indexed, err
+}
+
+// candidates returns the filter of the files that may match according to the index of
+// the repository. Without an index, or with one of another commit, every file may match.
+func (s *Searcher) candidates(repoPath, head string) (func(name string) bool, bool) {
+	all := func(string) bool { return true }
+	if s.indexRoot == "" || s.query.All() || head == "" {
+		return all, false
+	}
+
+	idx, err := index.Load(s.indexRoot, repoPath)
+	if err != nil || idx.Commit != head {
+		return all, false
+	}
+
+	candidate, err := idx.Candidates(s.query)
+	if err != nil {
+		return all, false
+	}
+	return candidate, true
+}
+
+// searchWorkingTree searches the files of a checkout
+func (s *Searcher) searchWorkingTree(ctx context.Context, repoPath string, names []string) ([]File, error) {
+	var files []File
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if s.Exhausted() {
+			break
+		}
+
+		// Files outside a sparse checkout are listed but absent, and
+		// submodules and symbolic links are not followed
+		info, err := os.Lstat(filepath.Join(repoPath, filepath.FromSlash(name)))
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(repoPath, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		if f, ok := s.SearchContent(name, content); ok {
+			files = append(files, f)
+		}
+	}
+
+	return files, nil
+}
+
+// searchTree searches the files in the tree of a commit
+func (s *Searcher) searchTree(ctx context.Context, repoPath, commit string, names []string) ([]File, error) {
+	var files []File
+	err := git.ReadBlobs(ctx, repoPath, commit, names, func(name string, content []byte) {
+		if s.Exhausted() {
+			return
+		}
+		if f, ok := s.SearchContent(name, content); ok {
+			files = append(files, f)
+		}
+	})
+	return files, err
+}
+
+// selects reports whether the file passes the path and type filters
+func (s *Searcher) selects(name string) bool {
+	if s.extensions != nil && !s.extensions[Extension(name)] {
+		return false
+	}
+
+	if len(s.paths) == 0 {
+		return true
+	}
+	for _, glob := range s.paths {
+		target := path.Base(name)
+		if strings.Contains(glob, "/") {
+			target = name
+		}
+		if matched, _ := path.Match(glob, target); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// reserve counts a matching line against MaxMatches, reporting whether it may be added
+func (s *Searcher) reserve() bool {
+	if s.maxMatches == 0 {
+		return true
+	}
+	return s.matches.Add(1) <= s.maxMatches
+}
+
+// Exhausted reports whether MaxMatches lines were found, further matches are dropped
+func (s *Searcher) Exhausted() bool {
+	return s.maxMatches > 0 && s.matches.Load() >= s.maxMatches
+}
+
+// IsBinary reports whether the content is binary, recognised by a NUL byte near the start
+func IsBinary(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), binaryProbe)], 0) >= 0
+}
+
+// SearchContent searches the content of a file, reporting whether it matched.
+// Binary content, recognised by a NUL byte near the start, never matches.
+func (s *Searcher) SearchContent(name string, content []byte) (File, bool) {
+	if IsBinary(content) {
+		return File{}, false
+	}
+
+	lines := strings.Split(string(content), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	f := File{Path: name}
+	// next is the index of the first line not yet added to the result
+	next := 0
+	for i, text := range lines {
+		if !s.re.MatchString(text) {
+			continue
+		}
+		if !s.reserve() {
+			break
+		}
+
+		for j := max(next, i-s.context); j < i; j++ {
+			f.Lines = append(f.Lines, Line{Number: j + 1, Text: strings.TrimSuffix(lines[j], "\r")})
+		}
+		if i >= next {
+			f.Lines = append(f.Lines, Line{Number: i + 1, Text: strings.TrimSuffix(text, "\r"), Match: true})
+		} else {
+			// The line was already added as context of the previous match
+			f.Lines[len(f.Lines)-(next-i)].Match = true
+		}
+
+		end := min(len(lines), i+1+s.context)
+		for j := max(next, i+1); j < end; j++ {
+			f.Lines = append(f.Lines, Line{Number: j + 1, Text: strings.TrimSuffix(lines[j], "\r")})
+		}
+		next = max(next, end)
+	}
+
+	return f, len(f.Lines) > 0
+}
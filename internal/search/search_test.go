@@ -0,0 +1,322 @@
+package search
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jonasbn/baseline/internal/index"
+	"github.com/jonasbn/baseline/internal/testutil"
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// setupRepository creates a checkout with the files committed
+func setupRepository(t *testing.T, files map[string]string) string {
+	t.Helper()
+	testutil.SetIdentity(t)
+
+	dir := filepath.Join(t.TempDir(), "repo")
+	testutil.InitRepository(t, dir, "")
+	testutil.CommitFiles(t, dir, files)
+	return dir
+}
+
+func TestSearchContentContext(t *testing.T) {
+	s, err := New(Options{Pattern: "x", Context: 1})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	f, ok := s.SearchContent("f.txt", []byte("a\nx1\nb\nx2\nc\nd\ne\nx3\r\n"))
+	if !ok {
+		t.Fatal("Expected a match")
+	}
+
+	expected := []Line{
+		{1, "a", false}, {2, "x1", true}, {3, "b", false}, {4, "x2", true}, {5, "c", false},
+		{7, "e", false}, {8, "x3", true},
+	}
+	if !reflect.DeepEqual(f.Lines, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, f.Lines)
+	}
+}
+
+func TestSearchContentBinary(t *testing.T) {
+	s, err := New(Options{Pattern: "needle", Literal: true})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if _, ok := s.SearchContent("bin", []byte("needle\x00\x01")); ok {
+		t.Error("Binary content should not match")
+	}
+}
+
+func TestSearchWorkingTree(t *testing.T) {
+	dir := setupRepository(t, map[string]string{
+		".gitignore":     "build/\n",
+		"main.go":        "package main\n\n// TODO: remove\n",
+		"docs/readme.md": "todo list\n",
+		"lib/util.py":    "# todo.later\n",
+	})
+	// Ignored and untracked files
+	if err := os.MkdirAll(filepath.Join(dir, "build"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "build", "out.go"), []byte("// TODO\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "new.go"), []byte("// todo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected []string
+	}{
+		{"ignore case", Options{Pattern: "todo", IgnoreCase: true}, []string{"docs/readme.md", "lib/util.py", "main.go", "new.go"}},
+		{"case sensitive", Options{Pattern: "TODO"}, []string{"main.go"}},
+		{"literal", Options{Pattern: "todo.later", Literal: true}, []string{"lib/util.py"}},
+		{"type", Options{Pattern: "(?i)todo", Types: []string{"go"}}, []string{"main.go", "new.go"}},
+		{"path", Options{Pattern: "todo", IgnoreCase: true, Paths: []string{"docs/*", "*.py"}}, []string{"docs/readme.md", "lib/util.py"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(tt.opts)
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+
+			result := s.Search(context.Background(), types.Repository{BaselinePath: dir})
+			if result.Error != nil {
+				t.Fatalf("Search failed: %v", result.Error)
+			}
+
+			var paths []string
+			for _, f := range result.Files {
+				paths = append(paths, f.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, paths)
+			}
+		})
+	}
+}
+
+func TestSearchMirror(t *testing.T) {
+	dir := setupRepository(t, map[string]string{
+		"main.go": "package main\n\nfunc main() {}\n",
+		"bin.dat": "main\x00",
+	})
+	mirror := filepath.Join(t.TempDir(), "repo.git")
+	testutil.Git(t, filepath.Dir(mirror), "clone", "-q", "--mirror", dir, mirror)
+
+	s, err := New(Options{Pattern: `func \w+`})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	result := s.Search(context.Background(), types.Repository{BaselinePath: mirror})
+	if result.Error != nil {
+		t.Fatalf("Search failed: %v", result.Error)
+	}
+
+	expected := []File{{Path: "main.go", Lines: []Line{{3, "func main() {}", true}}}}
+	if !reflect.DeepEqual(result.Files, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result.Files)
+	}
+}
+
+func TestSearchIndexed(t *testing.T) {
+	dir := setupRepository(t, map[string]string{
+		"a.go": "func Alpha() {}\n",
+		"b.go": "func Beta() {}\n",
+	})
+	root := filepath.Dir(dir)
+	repo := types.Repository{BaselinePath: dir}
+
+	s, err := New(Options{Pattern: "alpha", IgnoreCase: true, IndexRoot: root})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	// Without an index every file is searched
+	if result := s.Search(context.Background(), repo); result.Error != nil || result.Indexed || len(result.Files) != 1 {
+		t.Fatalf("Unexpected result without index: %+v", result)
+	}
+
+	if result := index.Refresh(context.Background(), root, repo); result.Error != nil {
+		t.Fatalf("Refresh failed: %v", result.Error)
+	}
+
+	// An untracked file is not in the index but still searched
+	if err := os.WriteFile(filepath.Join(dir, "c.go"), []byte("// alpha\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := s.Search(context.Background(), repo)
+	if result.Error != nil || !result.Indexed {
+		t.Fatalf("Expected an indexed search, got %+v", result)
+	}
+	if len(result.Files) != 2 || result.Files[0].Path != "a.go" || result.Files[1].Path != "c.go" {
+		t.Errorf("Unexpected files %+v", result.Files)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	for _, opts := range []Options{
+		{},
+		{Pattern: "("},
+		{Pattern: "x", Context: -1},
+		{Pattern: "x", Paths: []string{"[a-"}},
+		{Pattern: "x", Types: []string{"cobol"}},
+		{Pattern: "x", History: true, Context: 2},
+		{Pattern: "x", Since: "2024-01-01", IndexRoot: "baseline"},
+	} {
+		if _, err := New(opts); err == nil {
+			t.Errorf("Expected New(%+v) to fail", opts)
+		}
+	}
+}
+
+func TestSearchHistory(t *testing.T) {
+	dir := setupRepository(t, map[string]string{"main.go": "package main\n\nfunc legacy() {}\n"})
+
+	// The function is renamed on a branch and deleted on main, so only the tips keep traces
+	t.Setenv("GIT_COMMITTER_DATE", "2030-01-01T00:00:00Z")
+	testutil.Git(t, dir, "checkout", "-q", "-b", "feature")
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc legacy() {}\nfunc extra() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	testutil.Git(t, dir, "commit", "-q", "-am", "extra")
+	testutil.Git(t, dir, "checkout", "-q", "main")
+	t.Setenv("GIT_COMMITTER_DATE", "2031-01-01T00:00:00Z")
+	testutil.Git(t, dir, "rm", "-q", "main.go")
+	testutil.Git(t, dir, "commit", "-q", "-m", "remove")
+
+	s, err := New(Options{Pattern: "func (legacy|extra)", History: true})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	result := s.Search(context.Background(), types.Repository{BaselinePath: dir})
+	if result.Error != nil {
+		t.Fatalf("Search failed: %v", result.Error)
+	}
+	if len(result.Hits) != 2 {
+		t.Fatalf("Expected 2 hits at the tip of feature, got %+v", result.Hits)
+	}
+	for _, h := range result.Hits {
+		if h.Revisions != 1 || h.First.Date.Year() != 2030 || h.First != h.Last {
+			t.Errorf("Unexpected hit %+v", h)
+		}
+	}
+
+	// Every commit since the start covers the initial commit as well
+	s, err = New(Options{Pattern: "legacy", Literal: true, Since: "2000-01-01"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	result = s.Search(context.Background(), types.Repository{BaselinePath: dir})
+	if result.Error != nil {
+		t.Fatalf("Search failed: %v", result.Error)
+	}
+	if len(result.Hits) != 1 {
+		t.Fatalf("Expected 1 deduplicated hit, got %+v", result.Hits)
+	}
+	h := result.Hits[0]
+	if h.Revisions != 2 || h.Line != 3 || h.Last.Date.Year() != 2030 || h.First.Date.Year() == 2030 {
+		t.Errorf("Unexpected hit %+v", h)
+	}
+}
+
+func TestSearchHistoryRegexp(t *testing.T) {
+	dir := setupRepository(t, map[string]string{"main.go": "const Version = 12\nconst name = \"v12\"\nconst KELVIN = 1\n"})
+	repo := types.Repository{BaselinePath: dir}
+
+	// Perl classes, word boundaries and inline flags mean the same as in other searches
+	tests := []struct {
+		pattern    string
+		ignoreCase bool
+		expected   int
+	}{
+		{`\bVersion = \d+`, false, 1},
+		{`\d+`, false, 3},
+		{`(?i)version`, false, 1},
+		{`kelvin`, true, 1},
+		{`^const \w+ = "`, false, 1},
+		{`[[:upper:]]{6}`, false, 1},
+		{`\bv12\b$`, false, 0},
+	}
+	for _, tt := range tests {
+		s, err := New(Options{Pattern: tt.pattern, IgnoreCase: tt.ignoreCase, History: true})
+		if err != nil {
+			t.Fatalf("New(%q) failed: %v", tt.pattern, err)
+		}
+		result := s.Search(context.Background(), repo)
+		if result.Error != nil {
+			t.Fatalf("Search %q failed: %v", tt.pattern, result.Error)
+		}
+		if len(result.Hits) != tt.expected {
+			t.Errorf("Expected %d hits for %q, got %+v", tt.expected, tt.pattern, result.Hits)
+		}
+	}
+}
+
+func TestGrepPattern(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		ignoreCase bool
+		expected   []string
+	}{
+		{"legacy", false, []string{"-F", "-e", "legacy"}},
+		{`func \w+Handler\(`, false, []string{"-F", "-e", "Handler("}},
+		{"(?i)kelvin", false, []string{"-F", "-i", "-e", "ELVIN"}},
+		{"todo|fixme", true, []string{"-e", ""}},
+		{`\d+`, false, []string{"-e", ""}},
+	}
+	for _, tt := range tests {
+		s, err := New(Options{Pattern: tt.pattern, IgnoreCase: tt.ignoreCase})
+		if err != nil {
+			t.Fatalf("New(%q) failed: %v", tt.pattern, err)
+		}
+		if got := s.grepPattern(); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Expected %q for %q, got %q", tt.expected, tt.pattern, got)
+		}
+	}
+}
+
+func TestSearchLimits(t *testing.T) {
+	dir := setupRepository(t, map[string]string{
+		"a.txt": "match 1\nmatch 2\nmatch 3\n",
+		"b.txt": "match 4\nmatch 5\n",
+	})
+	repo := types.Repository{BaselinePath: dir}
+
+	s, err := New(Options{Pattern: "match", MaxMatches: 4})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	result := s.Search(context.Background(), repo)
+	if result.Error != nil || result.Matches() != 4 || !s.Exhausted() {
+		t.Errorf("Expected 4 matches and an exhausted searcher, got %d (%v)", result.Matches(), result.Error)
+	}
+
+	// A cancelled search fails instead of reading further files
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, opts := range []Options{{Pattern: "match"}, {Pattern: "match", History: true}} {
+		s, err := New(opts)
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		if result := s.Search(ctx, repo); result.Error == nil {
+			t.Errorf("%+v: expected an error for a cancelled search, got %d matches", opts, result.Matches())
+		}
+	}
+}
@@ -0,0 +1,483 @@
+package server
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jonasbn/baseline/internal/catalog"
+	"github.com/jonasbn/baseline/internal/git"
+	"github.com/jonasbn/baseline/internal/search"
+	"github.com/jonasbn/baseline/internal/types"
+	"github.com/jonasbn/baseline/internal/worker"
+)
+
+//go:embed ui
+var uiFiles embed.FS
+
+// DefaultAddress only accepts connections from the local machine
+const DefaultAddress = "127.0.0.1:8080"
+
+// rescanInterval is how long the list of repositories in the baseline is cached
+const rescanInterval = 30 * time.Second
+
+// maxFileSize is the largest file returned by the file endpoint
+const maxFileSize = 4 << 20
+
+// commitPattern matches the abbreviated or full commit hashes accepted as revisions
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{7,64}$`)
+
+// Options configures the server
+type Options struct {
+	// Root is the baseline directory
+	Root string
+	// Address is the host:port to listen on
+	Address string
+	// Threads is the number of repositories searched concurrently by a request
+	Threads int
+	// RequestTimeout bounds the time spent on a request, searches return the results
+	// found so far when it expires
+	RequestTimeout time.Duration
+	// MaxResults is the number of matching lines after which a search stops
+	MaxResults int
+	// MaxSearches is the number of searches run at the same time, further search
+	// requests are refused until one finishes
+	MaxSearches int
+	// UseIndex narrows searches down with the search indexes of the baseline
+	UseIndex bool
+	Verbose  bool
+}
+
+// Server serves the baseline over HTTP. It only reads from the baseline: the API has no
+// endpoint changing repositories, indexes or the catalog, and only GET requests are accepted.
+type Server struct {
+	opts Options
+
+	// searches holds a token for each search in progress
+	searches chan struct{}
+
+	mu      sync.Mutex
+	entries []catalog.Entry
+	scanned time.Time
+}
+
+// New creates a server for the options
+func New(opts Options) *Server {
+	if opts.Address == "" {
+		opts.Address = DefaultAddress
+	}
+	if opts.Threads <= 0 {
+		opts.Threads = 4
+	}
+	if opts.RequestTimeout <= 0 {
+		opts.RequestTimeout = 30 * time.Second
+	}
+	if opts.MaxResults <= 0 {
+		opts.MaxResults = 1000
+	}
+	if opts.MaxSearches <= 0 {
+		opts.MaxSearches = 4
+	}
+	return &Server{opts: opts, searches: make(chan struct{}, opts.MaxSearches)}
+}
+
+// Handler returns the HTTP handler of the API and the web UI
+func (s *Server) Handler() http.Handler {
+	ui, _ := fs.Sub(uiFiles, "ui")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/repositories", s.handleRepositories)
+	mux.HandleFunc("/api/search", s.handleSearch)
+	mux.HandleFunc("/api/file", s.handleFile)
+	mux.Handle("/", http.FileServer(http.FS(ui)))
+
+	return readOnly(mux)
+}
+
+// ListenAndServe serves until the context is cancelled, then shuts down gracefully
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.opts.Address)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves on the listener until the context is cancelled
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		// Leave room to write the response of a request that used its full time
+		WriteTimeout: s.opts.RequestTimeout + 10*time.Second,
+		IdleTimeout:  2 * time.Minute,
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+// readOnly rejects every method but GET and HEAD
+func readOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed, the baseline is read-only", r.Method))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// repositories returns the repositories of the baseline, rescanned at most every rescanInterval
+func (s *Server) repositories() ([]catalog.Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.entries != nil && time.Since(s.scanned) < rescanInterval {
+		return s.entries, nil
+	}
+
+	entries, err := catalog.Scan(s.opts.Root)
+	if err != nil {
+		return nil, err
+	}
+	s.entries = entries
+	s.scanned = time.Now()
+	return entries, nil
+}
+
+// repositoryResponse describes a repository of the baseline
+type repositoryResponse struct {
+	Path string `json:"path"`
+	types.Repository
+}
+
+// handleRepositories lists the repositories of the baseline, optionally of some owners
+// or matching patterns as with search --owner and --repo
+func (s *Server) handleRepositories(w http.ResponseWriter, r *http.Request) {
+	entries, err := s.repositories()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	query := r.URL.Query()
+	entries, err = catalog.Select(entries, query["owner"], query["repo"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	response := make([]repositoryResponse, len(entries))
+	for i, e := range entries {
+		response[i] = repositoryResponse{Path: e.Path, Repository: e.Repository}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// lineResponse is a line of a file, matching or shown as context
+type lineResponse struct {
+	Number int    `json:"number"`
+	Text   string `json:"text"`
+	Match  bool   `json:"match"`
+}
+
+// fileResponse holds the lines found in a file
+type fileResponse struct {
+	Repository string         `json:"repository"`
+	Path       string         `json:"path"`
+	Lines      []lineResponse `json:"lines"`
+}
+
+// revisionResponse is a commit a history match was found in
+type revisionResponse struct {
+	Commit string    `json:"commit"`
+	Date   time.Time `json:"date"`
+}
+
+// hitResponse is a line found in the history of a repository
+type hitResponse struct {
+	Repository string           `json:"repository"`
+	Path       string           `json:"path"`
+	Line       int              `json:"line"`
+	Text       string           `json:"text"`
+	First      revisionResponse `json:"first"`
+	Last       revisionResponse `json:"last"`
+	Revisions  int              `json:"revisions"`
+}
+
+// searchResponse is the outcome of a search
+type searchResponse struct {
+	Files        []fileResponse `json:"files"`
+	Hits         []hitResponse  `json:"hits,omitempty"`
+	Matches      int            `json:"matches"`
+	Repositories int            `json:"repositories"`
+	// Truncated is set when MaxResults was reached, TimedOut when the request timeout expired
+	Truncated bool     `json:"truncated"`
+	TimedOut  bool     `json:"timed_out"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// handleSearch searches the baseline. The parameters follow the flags of the search command:
+// q, fixed, ignore_case, context, owner, repo, path, type, history and since.
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	opts := search.Options{
+		Pattern:    query.Get("q"),
+		Literal:    isTrue(query.Get("fixed")),
+		IgnoreCase: isTrue(query.Get("ignore_case")),
+		Paths:      query["path"],
+		Types:      query["type"],
+		History:    isTrue(query.Get("history")),
+		Since:      query.Get("since"),
+		MaxMatches: s.opts.MaxResults,
+	}
+	if value := query.Get("context"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid context %q", value))
+			return
+		}
+		opts.Context = n
+	}
+	if s.opts.UseIndex && !opts.History && opts.Since == "" {
+		opts.IndexRoot = s.opts.Root
+	}
+
+	searcher, err := search.New(opts)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	entries, err := s.repositories()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	entries, err = catalog.Select(entries, query["owner"], query["repo"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repositories := make([]types.Repository, len(entries))
+	for i, e := range entries {
+		repositories[i] = e.Repository
+		repositories[i].FullName = e.Path
+	}
+
+	select {
+	case s.searches <- struct{}{}:
+		defer func() { <-s.searches }()
+	default:
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("%d searches in progress, try again later", s.opts.MaxSearches))
+		return
+	}
+
+	// Cancelling the context when the request ends stops the Git processes of the search
+	ctx, cancel := context.WithTimeout(r.Context(), s.opts.RequestTimeout)
+	defer cancel()
+
+	wp := worker.NewWorkerPool(s.opts.Threads, s.opts.Verbose)
+	resultChan := wp.SearchRepositories(ctx, repositories, searcher)
+
+	response := searchResponse{Files: []fileResponse{}, Repositories: len(repositories)}
+	for done := false; !done; {
+		select {
+		case result, ok := <-resultChan:
+			if !ok {
+				done = true
+				break
+			}
+			if response.Truncated {
+				continue
+			}
+			if result.Error != nil {
+				response.Errors = append(response.Errors, fmt.Sprintf("%s: %v", result.Repository.FullName, result.Error))
+				continue
+			}
+			response.add(result, s.opts.MaxResults)
+		case <-ctx.Done():
+			response.TimedOut = true
+			done = true
+		}
+	}
+	if searcher.Exhausted() {
+		response.Truncated = true
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// add adds the lines of a result until max matching lines were added
+func (r *searchResponse) add(result search.Result, max int) {
+	for _, h := range result.Hits {
+		if r.Matches == max {
+			r.Truncated = true
+			return
+		}
+		r.Hits = append(r.Hits, hitResponse{
+			Repository: result.Repository.FullName,
+			Path:       h.Path,
+			Line:       h.Line,
+			Text:       h.Text,
+			First:      revisionResponse{Commit: h.First.Commit, Date: h.First.Date},
+			Last:       revisionResponse{Commit: h.Last.Commit, Date: h.Last.Date},
+			Revisions:  h.Revisions,
+		})
+		r.Matches++
+	}
+
+	for _, f := range result.Files {
+		file := fileResponse{Repository: result.Repository.FullName, Path: f.Path}
+		for _, l := range f.Lines {
+			if l.Match {
+				if r.Matches == max {
+					r.Truncated = true
+					break
+				}
+				r.Matches++
+			}
+			file.Lines = append(file.Lines, lineResponse{Number: l.Number, Text: l.Text, Match: l.Match})
+		}
+		r.Files = append(r.Files, file)
+		if r.Truncated {
+			return
+		}
+	}
+}
+
+// fileContentResponse is the content of a file
+type fileContentResponse struct {
+	Repository string `json:"repository"`
+	Path       string `json:"path"`
+	Revision   string `json:"revision"`
+	Binary     bool   `json:"binary"`
+	Content    string `json:"content,omitempty"`
+}
+
+// handleFile returns a file of a repository as committed in HEAD, or in the commit given
+// as rev. Files are read from the Git object database, never from arbitrary paths.
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	repoPath, name, rev := query.Get("repo"), query.Get("path"), query.Get("rev")
+
+	if name == "" || strings.HasPrefix(name, "/") || strings.ContainsAny(name, "\n\x00") {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid path %q", name))
+		return
+	}
+	if rev == "" {
+		rev = "HEAD"
+	} else if !commitPattern.MatchString(rev) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid revision %q, use a commit hash", rev))
+		return
+	}
+
+	entries, err := s.repositories()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Only repositories of the baseline are served, looked up by their exact path
+	var entry *catalog.Entry
+	for i := range entries {
+		if entries[i].Path == repoPath {
+			entry = &entries[i]
+			break
+		}
+	}
+	if entry == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("repository %q not found", repoPath))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.opts.RequestTimeout)
+	defer cancel()
+
+	commit, err := git.ResolveCommit(entry.Repository.BaselinePath, rev)
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("revision %s not found in %s", rev, repoPath))
+		return
+	}
+
+	var content []byte
+	found := false
+	err = git.ReadBlobs(ctx, entry.Repository.BaselinePath, commit, []string{name}, func(_ string, c []byte) {
+		content, found = c, true
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Errorf("file %s not found in %s at %s", name, repoPath, rev))
+		return
+	}
+	if len(content) > maxFileSize {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("file %s is larger than %d bytes", name, maxFileSize))
+		return
+	}
+
+	response := fileContentResponse{Repository: repoPath, Path: name, Revision: commit}
+	if search.IsBinary(content) {
+		response.Binary = true
+	} else {
+		response.Content = string(content)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// isTrue parses boolean query parameters, accepting 1, true, yes and on
+func isTrue(value string) bool {
+	switch strings.ToLower(value) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// writeJSON writes the value as a JSON response
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	// A failed write means the client has gone away, there is nobody to report to
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// errorResponse is the body of failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeError writes the error as a JSON response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jonasbn/baseline/internal/catalog"
+	"github.com/jonasbn/baseline/internal/testutil"
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// setupBaseline creates a baseline with the checkout acme/api, recorded in the catalog
+func setupBaseline(t *testing.T) string {
+	t.Helper()
+	testutil.SetIdentity(t)
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "acme", "api")
+	testutil.InitRepository(t, dir, "")
+	testutil.CommitFiles(t, dir, map[string]string{"main.go": "package main\n\n// TODO: serve\nfunc main() {}\n"})
+
+	repo := types.Repository{Name: "api", FullName: "acme/api", Owner: "acme", Description: "The API", BaselinePath: dir}
+	if err := catalog.Record(root, []types.Repository{repo}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	return root
+}
+
+// get performs a request against the handler and decodes the JSON response
+func get(t *testing.T, h http.Handler, target string, params url.Values, v any) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target+"?"+params.Encode(), nil))
+	if v != nil {
+		if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+			t.Fatalf("%s: invalid JSON: %v", target, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestRepositories(t *testing.T) {
+	h := New(Options{Root: setupBaseline(t)}).Handler()
+
+	var repositories []map[string]any
+	if code := get(t, h, "/api/repositories", nil, &repositories); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if len(repositories) != 1 {
+		t.Fatalf("Expected 1 repository, got %v", repositories)
+	}
+	r := repositories[0]
+	if r["path"] != "acme/api" || r["description"] != "The API" {
+		t.Errorf("Unexpected repository %v", r)
+	}
+	if r["local_path"] != "" {
+		t.Errorf("Expected no local path, got %v", r["local_path"])
+	}
+
+	if get(t, h, "/api/repositories", url.Values{"owner": {"other"}}, &repositories); len(repositories) != 0 {
+		t.Errorf("Expected no repositories of other, got %v", repositories)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	h := New(Options{Root: setupBaseline(t)}).Handler()
+
+	var response searchResponse
+	code := get(t, h, "/api/search", url.Values{"q": {"todo"}, "ignore_case": {"true"}, "context": {"1"}}, &response)
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if response.Matches != 1 || len(response.Files) != 1 {
+		t.Fatalf("Expected 1 match, got %+v", response)
+	}
+	f := response.Files[0]
+	if f.Repository != "acme/api" || f.Path != "main.go" || len(f.Lines) != 3 || !f.Lines[1].Match || f.Lines[1].Number != 3 {
+		t.Errorf("Unexpected file %+v", f)
+	}
+
+	var failure errorResponse
+	if code := get(t, h, "/api/search", url.Values{"q": {"("}}, &failure); code != http.StatusBadRequest || failure.Error == "" {
+		t.Errorf("Expected 400 with an error for an invalid pattern, got %d %+v", code, failure)
+	}
+}
+
+func TestSearchMaxResults(t *testing.T) {
+	h := New(Options{Root: setupBaseline(t), MaxResults: 1}).Handler()
+
+	var response searchResponse
+	get(t, h, "/api/search", url.Values{"q": {"main"}}, &response)
+	if response.Matches != 1 || !response.Truncated {
+		t.Errorf("Expected 1 match and truncation, got %+v", response)
+	}
+}
+
+func TestFile(t *testing.T) {
+	h := New(Options{Root: setupBaseline(t)}).Handler()
+
+	var file fileContentResponse
+	code := get(t, h, "/api/file", url.Values{"repo": {"acme/api"}, "path": {"main.go"}}, &file)
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if !strings.Contains(file.Content, "TODO: serve") || file.Binary || len(file.Revision) != 40 {
+		t.Errorf("Unexpected file %+v", file)
+	}
+
+	tests := []struct {
+		params url.Values
+		code   int
+	}{
+		{url.Values{"repo": {"acme/api"}, "path": {"missing.go"}}, http.StatusNotFound},
+		{url.Values{"repo": {"acme/../acme/api"}, "path": {"main.go"}}, http.StatusNotFound},
+		{url.Values{"repo": {"acme/api"}, "path": {"/etc/passwd"}}, http.StatusBadRequest},
+		{url.Values{"repo": {"acme/api"}, "path": {"main.go"}, "rev": {"--all"}}, http.StatusBadRequest},
+		{url.Values{"repo": {"acme/api"}, "path": {"nope\nHEAD:main.go"}}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		var failure errorResponse
+		if code := get(t, h, "/api/file", tt.params, &failure); code != tt.code || failure.Error == "" {
+			t.Errorf("%v: expected %d with an error, got %d %+v", tt.params, tt.code, code, failure)
+		}
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	h := New(Options{Root: setupBaseline(t)}).Handler()
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, "/api/repositories", nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s: expected 405, got %d", method, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<title>baseline</title>") {
+		t.Errorf("Expected the web UI, got %d", rec.Code)
+	}
+}
+
+func TestSearchConcurrencyLimit(t *testing.T) {
+	srv := New(Options{Root: setupBaseline(t), MaxSearches: 1})
+	h := srv.Handler()
+
+	// Take the only search slot as a running search would
+	srv.searches <- struct{}{}
+	var failure errorResponse
+	if code := get(t, h, "/api/search", url.Values{"q": {"todo"}}, &failure); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 while a search runs, got %d %+v", code, failure)
+	}
+
+	<-srv.searches
+	var response searchResponse
+	if code := get(t, h, "/api/search", url.Values{"q": {"TODO"}}, &response); code != http.StatusOK || response.Matches != 1 {
+		t.Errorf("Expected 200 with 1 match once the search finished, got %d %+v", code, response)
+	}
+}
@@ -0,0 +1,152 @@
+package azuredevops
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// DefaultBaseURL is the base URL of the Azure DevOps Services
+const DefaultBaseURL = "https://dev.azure.com"
+
+// apiVersion is the Azure DevOps REST API version used for all requests
+const apiVersion = "7.1"
+
+// AzureDevOpsClient implements the RepositorySource interface for Azure DevOps Repos
+type AzureDevOpsClient struct {
+	token      string
+	httpClient *http.Client
+	baseURL    string
+}
+
+// AzureDevOpsRepository represents an Azure DevOps Git repository response
+type AzureDevOpsRepository struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	DefaultBranch string `json:"defaultBranch"`
+	RemoteURL     string `json:"remoteUrl"`
+	SSHURL        string `json:"sshUrl"`
+	WebURL        string `json:"webUrl"`
+	IsDisabled    bool   `json:"isDisabled"`
+	IsFork        bool   `json:"isFork"`
+	Size          int64  `json:"size"`
+	Project       struct {
+		Name       string `json:"name"`
+		Visibility string `json:"visibility"`
+	} `json:"project"`
+}
+
+// AzureDevOpsResponse represents the list response from Azure DevOps
+type AzureDevOpsResponse struct {
+	Value []AzureDevOpsRepository `json:"value"`
+	Count int                     `json:"count"`
+}
+
+// NewAzureDevOpsClient creates a new Azure DevOps client
+// baseURL defaults to https://dev.azure.com, set it for Azure DevOps Server collections
+// token should be a personal access token (PAT) with Code (Read) scope
+func NewAzureDevOpsClient(baseURL, token string) *AzureDevOpsClient {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	return &AzureDevOpsClient{
+		token: token,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+			// Azure DevOps answers unauthenticated requests with a redirect to
+			// the sign-in page, report that as an error instead of following it
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// GetName returns the source name
+func (a *AzureDevOpsClient) GetName() string {
+	return "azuredevops"
+}
+
+// GetRepositories fetches all Git repositories for the given organization.
+// The organization may be given as "org" to include every project,
+// or as "org/project" to limit the listing to a single project.
+func (a *AzureDevOpsClient) GetRepositories(ctx context.Context, organization string) ([]types.Repository, error) {
+	org, project, _ := strings.Cut(organization, "/")
+	if org == "" {
+		return nil, fmt.Errorf("invalid Azure DevOps organization: %q", organization)
+	}
+
+	// Without a project the organization-level endpoint lists the
+	// repositories of every project the token has access to
+	scope := url.PathEscape(org)
+	if project != "" {
+		scope += "/" + url.PathEscape(project)
+	}
+	listURL := fmt.Sprintf("%s/%s/_apis/git/repositories?api-version=%s", a.baseURL, scope, apiVersion)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", listURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if a.token != "" {
+		// PATs are sent as the password of basic auth with an empty username
+		auth := base64.StdEncoding.EncodeToString([]byte(":" + a.token))
+		req.Header.Set("Authorization", "Basic "+auth)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("azure DevOps API returned status %d", resp.StatusCode)
+	}
+
+	var response AzureDevOpsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	repos := make([]types.Repository, len(response.Value))
+	for i, repo := range response.Value {
+		repos[i] = a.convertToRepository(org, repo)
+	}
+
+	return repos, nil
+}
+
+func (a *AzureDevOpsClient) convertToRepository(org string, repo AzureDevOpsRepository) types.Repository {
+	// Repository names are only unique within a project, so the owner includes
+	// the project to lay repositories out as <org>/<project>/<repo>
+	owner := org + "/" + repo.Project.Name
+
+	return types.Repository{
+		ID:            repo.ID,
+		Name:          repo.Name,
+		FullName:      owner + "/" + repo.Name,
+		CloneURL:      repo.RemoteURL,
+		SSHURL:        repo.SSHURL,
+		HTTPSURL:      repo.WebURL,
+		Private:       repo.Project.Visibility != "public",
+		Owner:         owner,
+		DefaultBranch: strings.TrimPrefix(repo.DefaultBranch, "refs/heads/"),
+		Disabled:      repo.IsDisabled,
+		Fork:          repo.IsFork,
+		Size:          repo.Size / 1024,
+		// Repositories without commits have no default branch
+		Empty: repo.DefaultBranch == "",
+	}
+}
@@ -0,0 +1,64 @@
+package azuredevops
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetRepositoriesLayout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/contoso/_apis/git/repositories" {
+			t.Errorf("Unexpected request path '%s'", r.URL.Path)
+		}
+		if _, password, ok := r.BasicAuth(); !ok || password != "secret" {
+			t.Error("Expected PAT to be sent as basic auth password")
+		}
+
+		fmt.Fprint(w, `{"count": 2, "value": [
+			{"name": "api", "defaultBranch": "refs/heads/main", "remoteUrl": "https://contoso@dev.azure.com/contoso/Web/_git/api", "project": {"name": "Web", "visibility": "private"}},
+			{"name": "api", "isDisabled": true, "sshUrl": "git@ssh.dev.azure.com:v3/contoso/Mobile/api", "project": {"name": "Mobile", "visibility": "public"}}
+		]}`)
+	}))
+	defer server.Close()
+
+	client := NewAzureDevOpsClient(server.URL, "secret")
+	repos, err := client.GetRepositories(context.Background(), "contoso")
+	if err != nil {
+		t.Fatalf("GetRepositories failed: %v", err)
+	}
+
+	if len(repos) != 2 {
+		t.Fatalf("Expected 2 repositories, got %d", len(repos))
+	}
+
+	if repos[0].Owner == repos[1].Owner {
+		t.Errorf("Repositories with the same name in different projects must not share an owner, got '%s'", repos[0].Owner)
+	}
+
+	if repos[0].FullName != "contoso/Web/api" {
+		t.Errorf("Expected full name 'contoso/Web/api', got '%s'", repos[0].FullName)
+	}
+
+	if repos[0].DefaultBranch != "main" {
+		t.Errorf("Expected default branch 'main', got '%s'", repos[0].DefaultBranch)
+	}
+
+	if !repos[1].Disabled || repos[1].Private {
+		t.Error("isDisabled or visibility was not mapped correctly")
+	}
+}
+
+func TestGetRepositoriesRedirectIsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/_signin", http.StatusFound)
+	}))
+	defer server.Close()
+
+	client := NewAzureDevOpsClient(server.URL, "")
+	if _, err := client.GetRepositories(context.Background(), "contoso/Web"); err == nil {
+		t.Error("Expected a redirect to the sign-in page to be reported as an error")
+	}
+}
@@ -24,6 +24,7 @@ type BitbucketClient struct {
 
 // BitbucketRepository represents a Bitbucket repository response
 type BitbucketRepository struct {
+	UUID     string `json:"uuid"`
 	Name     string `json:"name"`
 	FullName string `json:"full_name"`
 	Links    struct {
@@ -42,6 +43,13 @@ type BitbucketRepository struct {
 	Owner       struct {
 		Username string `json:"username"`
 	} `json:"owner"`
+	MainBranch *struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+	Parent *struct {
+		FullName string `json:"full_name"`
+	} `json:"parent"`
+	Size int64 `json:"size"`
 }
 
 // BitbucketResponse represents the paginated response from Bitbucket
@@ -122,7 +130,7 @@ func (b *BitbucketClient) getRepositoriesPage(ctx context.Context, url string) (
 		fmt.Printf("curl -v -u '%s:[TOKEN]' \\\n", b.username)
 		fmt.Printf("  -H 'Accept: application/json' \\\n")
 		fmt.Printf("  '%s'\n", req.URL.String())
-		fmt.Println("========================================\n")
+		fmt.Print("========================================\n\n")
 
 		// Also dump the raw request if needed
 		if reqDump, err := httputil.DumpRequestOut(req, false); err == nil {
@@ -146,7 +154,7 @@ func (b *BitbucketClient) getRepositoriesPage(ctx context.Context, url string) (
 				fmt.Printf("  %s: %s\n", name, value)
 			}
 		}
-		fmt.Println("======================================\n")
+		fmt.Print("======================================\n\n")
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -181,7 +189,13 @@ func (b *BitbucketClient) convertToRepository(repo BitbucketRepository) types.Re
 		}
 	}
 
+	var defaultBranch string
+	if repo.MainBranch != nil {
+		defaultBranch = repo.MainBranch.Name
+	}
+
 	return types.Repository{
+		ID:          repo.UUID,
 		Name:        repo.Name,
 		FullName:    repo.FullName,
 		CloneURL:    cloneURL,
@@ -192,5 +206,11 @@ func (b *BitbucketClient) convertToRepository(repo BitbucketRepository) types.Re
 		UpdatedAt:   repo.UpdatedOn,
 		Language:    repo.Language,
 		Owner:       repo.Owner.Username,
+
+		DefaultBranch: defaultBranch,
+		Fork:          repo.Parent != nil,
+		Size:          repo.Size / 1024,
+		// Repositories without commits have no main branch
+		Empty: repo.MainBranch == nil,
 	}
 }
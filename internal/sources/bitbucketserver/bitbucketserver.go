@@ -0,0 +1,187 @@
+package bitbucketserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// BitbucketServerClient implements the RepositorySource interface for
+// Bitbucket Server and Bitbucket Data Center
+type BitbucketServerClient struct {
+	token      string
+	debug      bool
+	httpClient *http.Client
+	baseURL    string
+}
+
+// BitbucketServerRepository represents a Bitbucket Server repository response
+type BitbucketServerRepository struct {
+	ID          int    `json:"id"`
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Public      bool   `json:"public"`
+	Archived    bool   `json:"archived"`
+	Origin      *struct {
+		Slug string `json:"slug"`
+	} `json:"origin"`
+	Project struct {
+		Key  string `json:"key"`
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"project"`
+	Links struct {
+		Clone []struct {
+			Name string `json:"name"`
+			Href string `json:"href"`
+		} `json:"clone"`
+		Self []struct {
+			Href string `json:"href"`
+		} `json:"self"`
+	} `json:"links"`
+}
+
+// BitbucketServerResponse represents the paged response from Bitbucket Server
+type BitbucketServerResponse struct {
+	Values        []BitbucketServerRepository `json:"values"`
+	IsLastPage    bool                        `json:"isLastPage"`
+	NextPageStart int                         `json:"nextPageStart"`
+}
+
+// NewBitbucketServerClient creates a new Bitbucket Server client
+// baseURL is the address of the instance, e.g. https://bitbucket.example.com
+// token should be an HTTP access token, sent as a bearer token
+func NewBitbucketServerClient(baseURL, token string, debug bool) (*BitbucketServerClient, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("a base URL is required for Bitbucket Server (use --bitbucket-server-url)")
+	}
+
+	return &BitbucketServerClient{
+		token: token,
+		debug: debug,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		baseURL: strings.TrimSuffix(baseURL, "/") + "/rest/api/1.0",
+	}, nil
+}
+
+// GetName returns the source name
+func (b *BitbucketServerClient) GetName() string {
+	return "bitbucket-server"
+}
+
+// GetRepositories fetches all repositories for the given project key.
+// Personal projects are addressed as ~username.
+func (b *BitbucketServerClient) GetRepositories(ctx context.Context, organization string) ([]types.Repository, error) {
+	var allRepos []types.Repository
+	projectKey := normalizeProjectKey(organization)
+	start := 0
+	limit := 100
+
+	for {
+		pageURL := fmt.Sprintf("%s/projects/%s/repos?start=%d&limit=%d", b.baseURL, url.PathEscape(projectKey), start, limit)
+
+		response, err := b.getRepositoriesPage(ctx, pageURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch repositories for project %s: %w", projectKey, err)
+		}
+
+		for _, repo := range response.Values {
+			allRepos = append(allRepos, b.convertToRepository(repo))
+		}
+
+		if response.IsLastPage || len(response.Values) == 0 {
+			break
+		}
+		start = response.NextPageStart
+	}
+
+	return allRepos, nil
+}
+
+// normalizeProjectKey upper-cases regular project keys, which are case-insensitive
+// upper-case identifiers, while leaving personal project (~username) keys untouched
+func normalizeProjectKey(key string) string {
+	if strings.HasPrefix(key, "~") {
+		return key
+	}
+	return strings.ToUpper(key)
+}
+
+func (b *BitbucketServerClient) getRepositoriesPage(ctx context.Context, pageURL string) (*BitbucketServerResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	if b.debug {
+		fmt.Printf("DEBUG: Bitbucket Server request: %s %s\n", req.Method, req.URL.String())
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if b.debug {
+		fmt.Printf("DEBUG: Bitbucket Server response: %s\n", resp.Status)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bitbucket server API returned status %d", resp.StatusCode)
+	}
+
+	var response BitbucketServerResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &response, nil
+}
+
+func (b *BitbucketServerClient) convertToRepository(repo BitbucketServerRepository) types.Repository {
+	// Extract clone URLs, Bitbucket Server names the HTTP(S) link "http"
+	var cloneURL, sshURL string
+	for _, link := range repo.Links.Clone {
+		switch link.Name {
+		case "http", "https":
+			cloneURL = link.Href
+		case "ssh":
+			sshURL = link.Href
+		}
+	}
+
+	var htmlURL string
+	if len(repo.Links.Self) > 0 {
+		htmlURL = repo.Links.Self[0].Href
+	}
+
+	return types.Repository{
+		ID:          strconv.Itoa(repo.ID),
+		Name:        repo.Slug,
+		FullName:    repo.Project.Key + "/" + repo.Slug,
+		CloneURL:    cloneURL,
+		SSHURL:      sshURL,
+		HTTPSURL:    htmlURL,
+		Description: repo.Description,
+		Private:     !repo.Public,
+		Owner:       repo.Project.Key,
+		Archived:    repo.Archived,
+		Fork:        repo.Origin != nil,
+	}
+}
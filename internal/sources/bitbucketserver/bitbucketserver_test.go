@@ -0,0 +1,67 @@
+package bitbucketserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNormalizeProjectKey(t *testing.T) {
+	if key := normalizeProjectKey("plat"); key != "PLAT" {
+		t.Errorf("Expected 'PLAT', got '%s'", key)
+	}
+
+	if key := normalizeProjectKey("~jdoe"); key != "~jdoe" {
+		t.Errorf("Expected personal project key '~jdoe' to be unchanged, got '%s'", key)
+	}
+}
+
+func TestGetRepositoriesPaging(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/1.0/projects/PLAT/repos" {
+			t.Errorf("Unexpected request path '%s'", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Expected bearer authorization header, got '%s'", r.Header.Get("Authorization"))
+		}
+
+		switch r.URL.Query().Get("start") {
+		case "0":
+			fmt.Fprint(w, `{"isLastPage": false, "nextPageStart": 1, "values": [{"slug": "api", "project": {"key": "PLAT"},
+				"links": {"clone": [{"name": "http", "href": "https://bitbucket.example.com/scm/plat/api.git"}, {"name": "ssh", "href": "ssh://git@bitbucket.example.com:7999/plat/api.git"}]}}]}`)
+		case "1":
+			fmt.Fprint(w, `{"isLastPage": true, "values": [{"slug": "web", "public": true, "project": {"key": "PLAT"}}]}`)
+		default:
+			t.Errorf("Unexpected start parameter '%s'", r.URL.Query().Get("start"))
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewBitbucketServerClient(server.URL, "secret", false)
+	if err != nil {
+		t.Fatalf("NewBitbucketServerClient failed: %v", err)
+	}
+
+	repos, err := client.GetRepositories(context.Background(), "plat")
+	if err != nil {
+		t.Fatalf("GetRepositories failed: %v", err)
+	}
+
+	if len(repos) != 2 {
+		t.Fatalf("Expected 2 repositories, got %d", len(repos))
+	}
+
+	if repos[0].CloneURL != "https://bitbucket.example.com/scm/plat/api.git" {
+		t.Errorf("Unexpected clone URL '%s'", repos[0].CloneURL)
+	}
+
+	if repos[0].SSHURL != "ssh://git@bitbucket.example.com:7999/plat/api.git" {
+		t.Errorf("Unexpected SSH URL '%s'", repos[0].SSHURL)
+	}
+
+	if repos[0].Owner != "PLAT" || repos[0].FullName != "PLAT/api" {
+		t.Errorf("Unexpected owner/full name '%s' '%s'", repos[0].Owner, repos[0].FullName)
+	}
+}
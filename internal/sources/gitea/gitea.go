@@ -0,0 +1,178 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// GiteaClient implements the RepositorySource interface for Gitea and Forgejo
+type GiteaClient struct {
+	token      string
+	httpClient *http.Client
+	baseURL    string
+}
+
+// GiteaRepository represents a Gitea repository response
+type GiteaRepository struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	FullName    string    `json:"full_name"`
+	CloneURL    string    `json:"clone_url"`
+	SSHURL      string    `json:"ssh_url"`
+	HTMLURL     string    `json:"html_url"`
+	Description string    `json:"description"`
+	Private     bool      `json:"private"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Language    string    `json:"language"`
+	Owner       struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	DefaultBranch string   `json:"default_branch"`
+	Archived      bool     `json:"archived"`
+	Fork          bool     `json:"fork"`
+	Empty         bool     `json:"empty"`
+	Size          int64    `json:"size"`
+	Topics        []string `json:"topics"`
+}
+
+// NewGiteaClient creates a new Gitea client
+// baseURL is the address of the Gitea or Forgejo instance and is mandatory,
+// since there is no canonical hosted instance to fall back to
+func NewGiteaClient(baseURL, token string) (*GiteaClient, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("a base URL is required for Gitea/Forgejo (use --gitea-url)")
+	}
+
+	return &GiteaClient{
+		token: token,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		baseURL: strings.TrimSuffix(baseURL, "/") + "/api/v1",
+	}, nil
+}
+
+// GetName returns the source name
+func (g *GiteaClient) GetName() string {
+	return "gitea"
+}
+
+// GetRepositories fetches all repositories for the given organization or user
+func (g *GiteaClient) GetRepositories(ctx context.Context, organization string) ([]types.Repository, error) {
+	var allRepos []types.Repository
+	page := 1
+	// Gitea caps the page size at 50 by default (API.MAX_RESPONSE_ITEMS)
+	limit := 50
+
+	for {
+		repos, total, err := g.getRepositoriesPage(ctx, organization, page, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch repositories page %d: %w", page, err)
+		}
+
+		allRepos = append(allRepos, repos...)
+
+		// Servers may return fewer repositories per page than requested, only an empty
+		// page or the total count ends the listing
+		if len(repos) == 0 || (total >= 0 && len(allRepos) >= total) {
+			break
+		}
+		page++
+	}
+
+	return allRepos, nil
+}
+
+// getRepositoriesPage returns a page of repositories and the total count reported in the
+// X-Total-Count header, -1 when it is missing
+func (g *GiteaClient) getRepositoriesPage(ctx context.Context, organization string, page, limit int) ([]types.Repository, int, error) {
+	// Try organization endpoint first, fall back to user endpoint if 404
+	url := fmt.Sprintf("%s/orgs/%s/repos?page=%d&limit=%d", g.baseURL, organization, page, limit)
+
+	resp, err := g.get(ctx, url)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		// Try user endpoint if organization endpoint returns 404
+		userURL := fmt.Sprintf("%s/users/%s/repos?page=%d&limit=%d", g.baseURL, organization, page, limit)
+
+		resp, err = g.get(ctx, userURL)
+		if err != nil {
+			return nil, 0, fmt.Errorf("user request: %w", err)
+		}
+		defer resp.Body.Close()
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("Gitea API returned status %d", resp.StatusCode)
+	}
+
+	var giteaRepos []GiteaRepository
+	if err := json.NewDecoder(resp.Body).Decode(&giteaRepos); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	repos := make([]types.Repository, len(giteaRepos))
+	for i, repo := range giteaRepos {
+		repos[i] = g.convertToRepository(repo)
+	}
+
+	total, err := strconv.Atoi(resp.Header.Get("X-Total-Count"))
+	if err != nil {
+		total = -1
+	}
+
+	return repos, total, nil
+}
+
+func (g *GiteaClient) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if g.token != "" {
+		req.Header.Set("Authorization", "token "+g.token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+
+	return resp, nil
+}
+
+func (g *GiteaClient) convertToRepository(repo GiteaRepository) types.Repository {
+	return types.Repository{
+		ID:          strconv.FormatInt(repo.ID, 10),
+		Name:        repo.Name,
+		FullName:    repo.FullName,
+		CloneURL:    repo.CloneURL,
+		SSHURL:      repo.SSHURL,
+		HTTPSURL:    repo.HTMLURL,
+		Description: repo.Description,
+		Private:     repo.Private,
+		UpdatedAt:   repo.UpdatedAt,
+		Language:    repo.Language,
+		Owner:       repo.Owner.Login,
+
+		DefaultBranch: repo.DefaultBranch,
+		Archived:      repo.Archived,
+		Fork:          repo.Fork,
+		Empty:         repo.Empty,
+		Size:          repo.Size,
+		Topics:        repo.Topics,
+	}
+}
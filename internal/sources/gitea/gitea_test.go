@@ -0,0 +1,101 @@
+package gitea
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNewGiteaClientRequiresBaseURL(t *testing.T) {
+	if _, err := NewGiteaClient("", "token"); err == nil {
+		t.Error("NewGiteaClient should fail without a base URL")
+	}
+}
+
+func TestGetRepositoriesUserFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			t.Errorf("Expected token authorization header, got '%s'", r.Header.Get("Authorization"))
+		}
+
+		switch r.URL.Path {
+		case "/api/v1/orgs/alice/repos":
+			w.WriteHeader(http.StatusNotFound)
+		case "/api/v1/users/alice/repos":
+			if r.URL.Query().Get("page") != "1" {
+				fmt.Fprint(w, `[]`)
+				return
+			}
+			fmt.Fprint(w, `[{"name": "dotfiles", "full_name": "alice/dotfiles", "clone_url": "https://git.example.com/alice/dotfiles.git", "owner": {"login": "alice"}}]`)
+		default:
+			t.Errorf("Unexpected request path '%s'", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewGiteaClient(server.URL+"/", "secret")
+	if err != nil {
+		t.Fatalf("NewGiteaClient failed: %v", err)
+	}
+
+	repos, err := client.GetRepositories(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("GetRepositories failed: %v", err)
+	}
+
+	if len(repos) != 1 || repos[0].Owner != "alice" || repos[0].Name != "dotfiles" {
+		t.Errorf("Expected alice/dotfiles, got %+v", repos)
+	}
+}
+
+func TestGetRepositoriesPaging(t *testing.T) {
+	// The server returns at most 30 repositories per page, fewer than requested
+	const pageSize, count = 30, 75
+	tests := []struct {
+		name       string
+		totalCount bool
+		requests   int
+	}{
+		{"empty page", false, 4},
+		{"total count", true, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+				var repos []string
+				for i := (page - 1) * pageSize; i < min(page*pageSize, count); i++ {
+					repos = append(repos, fmt.Sprintf(`{"name": "repo%d", "full_name": "acme/repo%d", "owner": {"login": "acme"}}`, i, i))
+				}
+				if tt.totalCount {
+					w.Header().Set("X-Total-Count", strconv.Itoa(count))
+				}
+				fmt.Fprintf(w, "[%s]", strings.Join(repos, ","))
+			}))
+			defer server.Close()
+
+			client, err := NewGiteaClient(server.URL, "")
+			if err != nil {
+				t.Fatalf("NewGiteaClient failed: %v", err)
+			}
+
+			repos, err := client.GetRepositories(context.Background(), "acme")
+			if err != nil {
+				t.Fatalf("GetRepositories failed: %v", err)
+			}
+			if len(repos) != count {
+				t.Errorf("Expected %d repositories, got %d", count, len(repos))
+			}
+			if requests != tt.requests {
+				t.Errorf("Expected %d requests, got %d", tt.requests, requests)
+			}
+		})
+	}
+}
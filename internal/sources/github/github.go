@@ -2,14 +2,23 @@ package github
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jonasbn/baseline/internal/types"
 )
 
+// DefaultAPIURL is the API base URL of github.com
+const DefaultAPIURL = "https://api.github.com"
+
 // GitHubClient implements the RepositorySource interface for GitHub
 type GitHubClient struct {
 	token      string
@@ -19,6 +28,7 @@ type GitHubClient struct {
 
 // GitHubRepository represents a GitHub repository response
 type GitHubRepository struct {
+	ID          int64     `json:"id"`
 	Name        string    `json:"name"`
 	FullName    string    `json:"full_name"`
 	CloneURL    string    `json:"clone_url"`
@@ -31,6 +41,15 @@ type GitHubRepository struct {
 	Owner       struct {
 		Login string `json:"login"`
 	} `json:"owner"`
+	DefaultBranch string   `json:"default_branch"`
+	Archived      bool     `json:"archived"`
+	Fork          bool     `json:"fork"`
+	Disabled      bool     `json:"disabled"`
+	Size          int64    `json:"size"`
+	Topics        []string `json:"topics"`
+	// PushedAt is null or the creation time for repositories nothing was pushed to
+	CreatedAt time.Time  `json:"created_at"`
+	PushedAt  *time.Time `json:"pushed_at"`
 }
 
 // NewGitHubClient creates a new GitHub client
@@ -40,8 +59,81 @@ func NewGitHubClient(token string) *GitHubClient {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		baseURL: "https://api.github.com",
+		baseURL: DefaultAPIURL,
+	}
+}
+
+// NewGitHubEnterpriseClient creates a new GitHub client for a GitHub Enterprise Server instance
+// apiURL may be given with or without the /api/v3 prefix, e.g. https://github.example.com
+// caFile is an optional PEM bundle trusted in addition to the system certificates,
+// for instances using an internal certificate authority
+func NewGitHubEnterpriseClient(token, apiURL, caFile string) (*GitHubClient, error) {
+	baseURL, err := normalizeAPIURL(apiURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := NewGitHubClient(token)
+	client.baseURL = baseURL
+
+	if caFile != "" {
+		transport, err := newCATransport(caFile)
+		if err != nil {
+			return nil, err
+		}
+		client.httpClient.Transport = transport
+	}
+
+	return client, nil
+}
+
+// normalizeAPIURL turns a GitHub Enterprise Server address into its REST API base URL
+func normalizeAPIURL(apiURL string) (string, error) {
+	if apiURL == "" {
+		return DefaultAPIURL, nil
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(apiURL, "/"))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid GitHub API URL: %q", apiURL)
+	}
+
+	// github.com serves its API from a separate host without a prefix
+	if u.Host == "github.com" || u.Host == "api.github.com" {
+		return DefaultAPIURL, nil
 	}
+
+	// GitHub Enterprise Server serves its REST API below /api/v3
+	if !strings.HasSuffix(u.Path, "/api/v3") {
+		u.Path = strings.TrimSuffix(u.Path, "/api") + "/api/v3"
+	}
+
+	return u.String(), nil
+}
+
+// newCATransport creates an HTTP transport trusting the certificates in caFile
+func newCATransport(caFile string) (*http.Transport, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle %s: %w", caFile, err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", caFile)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	return transport, nil
 }
 
 // GetName returns the source name
@@ -145,6 +237,7 @@ func (g *GitHubClient) convertToRepository(repo GitHubRepository) types.Reposito
 	}
 
 	return types.Repository{
+		ID:          strconv.FormatInt(repo.ID, 10),
 		Name:        repo.Name,
 		FullName:    repo.FullName,
 		CloneURL:    repo.CloneURL,
@@ -155,5 +248,23 @@ func (g *GitHubClient) convertToRepository(repo GitHubRepository) types.Reposito
 		UpdatedAt:   repo.UpdatedAt,
 		Language:    language,
 		Owner:       repo.Owner.Login,
+
+		DefaultBranch: repo.DefaultBranch,
+		Archived:      repo.Archived,
+		Fork:          repo.Fork,
+		Disabled:      repo.Disabled,
+		Size:          repo.Size,
+		Topics:        repo.Topics,
+		Empty:         repo.isEmpty(),
+	}
+}
+
+// isEmpty reports whether the repository has no commits. GitHub reports a size of 0
+// for empty repositories, but also for new ones until it has computed their size, so
+// only repositories nothing was pushed to since their creation are empty.
+func (repo GitHubRepository) isEmpty() bool {
+	if repo.Size > 0 {
+		return false
 	}
+	return repo.PushedAt == nil || !repo.PushedAt.After(repo.CreatedAt)
 }
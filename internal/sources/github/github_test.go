@@ -0,0 +1,123 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeAPIURL(t *testing.T) {
+	tests := map[string]string{
+		"":                                    DefaultAPIURL,
+		"https://github.com":                  DefaultAPIURL,
+		"https://api.github.com/":             DefaultAPIURL,
+		"https://ghe.example.com":             "https://ghe.example.com/api/v3",
+		"https://ghe.example.com/":            "https://ghe.example.com/api/v3",
+		"https://ghe.example.com/api":         "https://ghe.example.com/api/v3",
+		"https://ghe.example.com/api/v3":      "https://ghe.example.com/api/v3",
+		"https://ghe.example.com:8443/api/v3": "https://ghe.example.com:8443/api/v3",
+	}
+
+	for input, expected := range tests {
+		actual, err := normalizeAPIURL(input)
+		if err != nil {
+			t.Errorf("normalizeAPIURL(%q) failed: %v", input, err)
+			continue
+		}
+		if actual != expected {
+			t.Errorf("normalizeAPIURL(%q): expected '%s', got '%s'", input, expected, actual)
+		}
+	}
+
+	if _, err := normalizeAPIURL("ghe.example.com"); err == nil {
+		t.Error("normalizeAPIURL should reject URLs without a scheme")
+	}
+}
+
+func TestGitHubEnterpriseServer(t *testing.T) {
+	// The TLS test server stands in for a GHES instance with an internal certificate
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			t.Errorf("Expected token authorization header, got '%s'", r.Header.Get("Authorization"))
+		}
+
+		switch r.URL.Path {
+		case "/api/v3/orgs/platform/repos":
+			fmt.Fprint(w, `[{"id": 42, "name": "api", "full_name": "platform/api", "owner": {"login": "platform"}}]`)
+		default:
+			t.Errorf("Unexpected request path '%s'", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0644); err != nil {
+		t.Fatalf("Failed to write CA bundle: %v", err)
+	}
+
+	// Without the CA bundle the internal certificate is not trusted
+	untrusted, err := NewGitHubEnterpriseClient("secret", server.URL, "")
+	if err != nil {
+		t.Fatalf("NewGitHubEnterpriseClient failed: %v", err)
+	}
+	if _, err := untrusted.GetRepositories(context.Background(), "platform"); err == nil {
+		t.Error("Expected an error for an untrusted certificate")
+	}
+
+	client, err := NewGitHubEnterpriseClient("secret", server.URL, caFile)
+	if err != nil {
+		t.Fatalf("NewGitHubEnterpriseClient failed: %v", err)
+	}
+
+	repos, err := client.GetRepositories(context.Background(), "platform")
+	if err != nil {
+		t.Fatalf("GetRepositories failed: %v", err)
+	}
+
+	if len(repos) != 1 || repos[0].FullName != "platform/api" || repos[0].ID != "42" {
+		t.Errorf("Expected platform/api, got %+v", repos)
+	}
+}
+
+func TestNewCATransportInvalidBundle(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0644); err != nil {
+		t.Fatalf("Failed to write CA bundle: %v", err)
+	}
+
+	if _, err := NewGitHubEnterpriseClient("", "https://ghe.example.com", caFile); err == nil {
+		t.Error("Expected an error for a CA bundle without certificates")
+	}
+}
+
+func TestEmptyRepositories(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		expected bool
+	}{
+		{"never pushed", `{"size": 0, "created_at": "2025-10-01T10:00:00Z", "pushed_at": null}`, true},
+		{"pushed at creation", `{"size": 0, "created_at": "2025-10-01T10:00:00Z", "pushed_at": "2025-10-01T10:00:00Z"}`, true},
+		{"size not computed yet", `{"size": 0, "created_at": "2025-10-01T10:00:00Z", "pushed_at": "2025-10-01T10:05:00Z"}`, false},
+		{"with content", `{"size": 120, "created_at": "2025-10-01T10:00:00Z", "pushed_at": "2025-10-01T10:00:00Z"}`, false},
+	}
+
+	client := NewGitHubClient("")
+	for _, tt := range tests {
+		var repo GitHubRepository
+		if err := json.Unmarshal([]byte(tt.json), &repo); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := client.convertToRepository(repo).Empty; got != tt.expected {
+			t.Errorf("%s: expected empty %t, got %t", tt.name, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// DefaultBaseURL is the base URL of the hosted GitLab instance
+const DefaultBaseURL = "https://gitlab.com"
+
+// GitLabClient implements the RepositorySource interface for GitLab
+type GitLabClient struct {
+	token      string
+	httpClient *http.Client
+	baseURL    string
+}
+
+// GitLabProject represents a GitLab project response
+type GitLabProject struct {
+	ID                int       `json:"id"`
+	Name              string    `json:"name"`
+	Path              string    `json:"path"`
+	PathWithNamespace string    `json:"path_with_namespace"`
+	HTTPURLToRepo     string    `json:"http_url_to_repo"`
+	SSHURLToRepo      string    `json:"ssh_url_to_repo"`
+	WebURL            string    `json:"web_url"`
+	Description       *string   `json:"description"`
+	Visibility        string    `json:"visibility"`
+	LastActivityAt    time.Time `json:"last_activity_at"`
+	DefaultBranch     string    `json:"default_branch"`
+	Namespace         struct {
+		FullPath string `json:"full_path"`
+	} `json:"namespace"`
+	Archived          bool     `json:"archived"`
+	EmptyRepo         bool     `json:"empty_repo"`
+	Topics            []string `json:"topics"`
+	ForkedFromProject *struct {
+		ID int `json:"id"`
+	} `json:"forked_from_project"`
+}
+
+// NewGitLabClient creates a new GitLab client
+// baseURL is the address of the GitLab instance, e.g. https://gitlab.example.com
+// token may be a personal, group or project access token
+func NewGitLabClient(baseURL, token string) *GitLabClient {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	return &GitLabClient{
+		token: token,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		baseURL: strings.TrimSuffix(baseURL, "/") + "/api/v4",
+	}
+}
+
+// GetName returns the source name
+func (g *GitLabClient) GetName() string {
+	return "gitlab"
+}
+
+// GetRepositories fetches all projects for the given group, including projects in nested subgroups
+func (g *GitLabClient) GetRepositories(ctx context.Context, organization string) ([]types.Repository, error) {
+	var allRepos []types.Repository
+
+	// The group is addressed by its URL-encoded full path, e.g. parent%2Fchild
+	query := url.Values{}
+	query.Set("include_subgroups", "true")
+	query.Set("pagination", "keyset")
+	query.Set("order_by", "id")
+	query.Set("sort", "asc")
+	query.Set("per_page", "100")
+	nextURL := fmt.Sprintf("%s/groups/%s/projects?%s", g.baseURL, url.PathEscape(organization), query.Encode())
+
+	for nextURL != "" {
+		repos, next, err := g.getRepositoriesPage(ctx, nextURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch projects for group %s: %w", organization, err)
+		}
+
+		allRepos = append(allRepos, repos...)
+		nextURL = next
+	}
+
+	return allRepos, nil
+}
+
+func (g *GitLabClient) getRepositoriesPage(ctx context.Context, pageURL string) ([]types.Repository, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if g.token != "" {
+		req.Header.Set("PRIVATE-TOKEN", g.token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("GitLab API returned status %d", resp.StatusCode)
+	}
+
+	var projects []GitLabProject
+	if err := json.NewDecoder(resp.Body).Decode(&projects); err != nil {
+		return nil, "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	repos := make([]types.Repository, len(projects))
+	for i, project := range projects {
+		repos[i] = g.convertToRepository(project)
+	}
+
+	return repos, nextLink(resp.Header.Get("Link")), nil
+}
+
+// nextLink extracts the rel="next" URL from a Link header, as used by keyset pagination
+func nextLink(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		sections := strings.Split(part, ";")
+		if len(sections) < 2 {
+			continue
+		}
+
+		for _, param := range sections[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				link := strings.TrimSpace(sections[0])
+				return strings.TrimSuffix(strings.TrimPrefix(link, "<"), ">")
+			}
+		}
+	}
+
+	return ""
+}
+
+func (g *GitLabClient) convertToRepository(project GitLabProject) types.Repository {
+	description := ""
+	if project.Description != nil {
+		description = *project.Description
+	}
+
+	// The namespace full path preserves the subgroup hierarchy, so the
+	// on-disk layout becomes targetDir/group/subgroup/project
+	owner := project.Namespace.FullPath
+	if owner == "" {
+		owner = strings.TrimSuffix(project.PathWithNamespace, "/"+project.Path)
+	}
+
+	return types.Repository{
+		ID:          strconv.Itoa(project.ID),
+		Name:        project.Path,
+		FullName:    project.PathWithNamespace,
+		CloneURL:    project.HTTPURLToRepo,
+		SSHURL:      project.SSHURLToRepo,
+		HTTPSURL:    project.WebURL,
+		Description: description,
+		Private:     project.Visibility != "public",
+		UpdatedAt:   project.LastActivityAt,
+		Owner:       owner,
+
+		DefaultBranch: project.DefaultBranch,
+		Archived:      project.Archived,
+		Fork:          project.ForkedFromProject != nil,
+		Empty:         project.EmptyRepo,
+		Topics:        project.Topics,
+	}
+}
@@ -0,0 +1,61 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNextLink(t *testing.T) {
+	header := `<https://gitlab.example.com/api/v4/groups/acme/projects?id_after=42&per_page=100>; rel="next", <https://gitlab.example.com/api/v4/groups/acme/projects?per_page=100>; rel="first"`
+
+	expected := "https://gitlab.example.com/api/v4/groups/acme/projects?id_after=42&per_page=100"
+	if link := nextLink(header); link != expected {
+		t.Errorf("Expected next link '%s', got '%s'", expected, link)
+	}
+
+	if link := nextLink(`<https://gitlab.example.com/api/v4/groups/acme/projects>; rel="first"`); link != "" {
+		t.Errorf("Expected no next link, got '%s'", link)
+	}
+}
+
+func TestGetRepositoriesSubgroups(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/groups/acme%2Fplatform/projects" {
+			t.Errorf("Unexpected request path '%s'", r.URL.EscapedPath())
+		}
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			t.Errorf("Expected PRIVATE-TOKEN header to be set")
+		}
+
+		if r.URL.Query().Get("id_after") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/api/v4/groups/acme%%2Fplatform/projects?id_after=1>; rel="next"`, server.URL))
+			fmt.Fprint(w, `[{"id": 1, "path": "api", "path_with_namespace": "acme/platform/api", "visibility": "private", "namespace": {"full_path": "acme/platform"}}]`)
+			return
+		}
+
+		fmt.Fprint(w, `[{"id": 2, "path": "tools", "path_with_namespace": "acme/platform/infra/tools", "visibility": "public", "namespace": {"full_path": "acme/platform/infra"}}]`)
+	}))
+	defer server.Close()
+
+	client := NewGitLabClient(server.URL, "secret")
+	repos, err := client.GetRepositories(context.Background(), "acme/platform")
+	if err != nil {
+		t.Fatalf("GetRepositories failed: %v", err)
+	}
+
+	if len(repos) != 2 {
+		t.Fatalf("Expected 2 repositories, got %d", len(repos))
+	}
+
+	if repos[1].Owner != "acme/platform/infra" || repos[1].Name != "tools" {
+		t.Errorf("Expected subgroup project 'acme/platform/infra/tools', got '%s/%s'", repos[1].Owner, repos[1].Name)
+	}
+
+	if !repos[0].Private || repos[1].Private {
+		t.Error("Visibility was not mapped to Private correctly")
+	}
+}
@@ -0,0 +1,124 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jonasbn/baseline/internal/git"
+	"github.com/jonasbn/baseline/internal/remote"
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// LocalClient implements the RepositorySource interface for existing
+// clones found in a directory tree on the local filesystem
+type LocalClient struct {
+	root    string
+	offline bool
+	debug   bool
+}
+
+// NewLocalClient creates a new local client scanning root for Git repositories
+// When offline is set, the baseline is cloned from the local clones themselves
+// instead of from their origin remote, so nothing is downloaded
+func NewLocalClient(root string, offline, debug bool) (*LocalClient, error) {
+	if root == "" {
+		return nil, fmt.Errorf("a directory to scan is required for the local source (use --local-root)")
+	}
+
+	if root == "~" || strings.HasPrefix(root, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand %s: %w", root, err)
+		}
+		root = filepath.Join(home, strings.TrimPrefix(root, "~"))
+	}
+
+	return &LocalClient{
+		root:    root,
+		offline: offline,
+		debug:   debug,
+	}, nil
+}
+
+// GetName returns the source name
+func (l *LocalClient) GetName() string {
+	return "local"
+}
+
+// GetRepositories scans the directory tree for Git repositories and
+// describes them by their origin remote. The organization is not used.
+func (l *LocalClient) GetRepositories(ctx context.Context, organization string) ([]types.Repository, error) {
+	paths, err := git.FindRepositories(l.root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", l.root, err)
+	}
+
+	var repos []types.Repository
+	seen := make(map[string]string)
+
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		repo, err := l.convertToRepository(path)
+		if err != nil {
+			if l.debug {
+				fmt.Printf("Skipping %s: %v\n", path, err)
+			}
+			continue
+		}
+
+		// Several clones of the same repository are adopted once
+		if first, ok := seen[repo.FullName]; ok {
+			if l.debug {
+				fmt.Printf("Skipping %s: %s is already provided by %s\n", path, repo.FullName, first)
+			}
+			continue
+		}
+		seen[repo.FullName] = path
+
+		repos = append(repos, repo)
+	}
+
+	return repos, nil
+}
+
+func (l *LocalClient) convertToRepository(path string) (types.Repository, error) {
+	originURL, err := git.RemoteURL(path, "origin")
+	if err != nil {
+		return types.Repository{}, fmt.Errorf("no origin remote")
+	}
+
+	r, err := remote.Parse(originURL)
+	if err != nil {
+		return types.Repository{}, err
+	}
+
+	if r.Host == "" || r.Owner == "" {
+		return types.Repository{}, fmt.Errorf("origin %s is not a hosted repository", originURL)
+	}
+
+	repo := types.Repository{
+		Name:      r.Name,
+		FullName:  r.Path,
+		CloneURL:  originURL,
+		Owner:     r.Owner,
+		LocalPath: path,
+	}
+
+	if strings.HasPrefix(originURL, "https://") || strings.HasPrefix(originURL, "http://") {
+		repo.HTTPSURL = originURL
+	} else {
+		repo.SSHURL = originURL
+	}
+
+	if l.offline {
+		repo.CloneURL = path
+	}
+
+	return repo, nil
+}
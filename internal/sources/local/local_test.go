@@ -0,0 +1,65 @@
+package local
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jonasbn/baseline/internal/testutil"
+)
+
+func TestGetRepositories(t *testing.T) {
+	root := t.TempDir()
+	testutil.InitRepository(t, filepath.Join(root, "work", "api"), "git@github.com:acme/api.git")
+	testutil.InitRepository(t, filepath.Join(root, "work", "api-copy"), "https://github.com/acme/api.git")
+	testutil.InitRepository(t, filepath.Join(root, "oss", "tools"), "https://gitlab.com/jdoe/group/tools.git")
+	testutil.InitRepository(t, filepath.Join(root, "scratch"), "")
+
+	client, err := NewLocalClient(root, false, false)
+	if err != nil {
+		t.Fatalf("NewLocalClient failed: %v", err)
+	}
+
+	repos, err := client.GetRepositories(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetRepositories failed: %v", err)
+	}
+
+	// The duplicate clone and the repository without origin are skipped
+	if len(repos) != 2 {
+		t.Fatalf("Expected 2 repositories, got %+v", repos)
+	}
+
+	tools := repos[0]
+	if tools.Owner != "jdoe/group" || tools.Name != "tools" || tools.HTTPSURL == "" {
+		t.Errorf("Unexpected repository %+v", tools)
+	}
+
+	api := repos[1]
+	if api.Owner != "acme" || api.Name != "api" || api.CloneURL != "git@github.com:acme/api.git" || api.SSHURL != api.CloneURL {
+		t.Errorf("Unexpected repository %+v", api)
+	}
+
+	if api.LocalPath != filepath.Join(root, "work", "api") {
+		t.Errorf("Expected local path to point at the existing clone, got '%s'", api.LocalPath)
+	}
+}
+
+func TestGetRepositoriesOffline(t *testing.T) {
+	root := t.TempDir()
+	testutil.InitRepository(t, filepath.Join(root, "api"), "https://github.com/acme/api.git")
+
+	client, err := NewLocalClient(root, true, false)
+	if err != nil {
+		t.Fatalf("NewLocalClient failed: %v", err)
+	}
+
+	repos, err := client.GetRepositories(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetRepositories failed: %v", err)
+	}
+
+	if len(repos) != 1 || repos[0].CloneURL != repos[0].LocalPath {
+		t.Errorf("Expected offline mode to clone from the local path, got %+v", repos)
+	}
+}
@@ -0,0 +1,175 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jonasbn/baseline/internal/remote"
+	"github.com/jonasbn/baseline/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestClient implements the RepositorySource interface for a
+// hand-curated manifest file listing repositories
+type ManifestClient struct {
+	path string
+}
+
+// ManifestEntry represents a repository in the manifest file
+type ManifestEntry struct {
+	CloneURL    string `yaml:"clone_url"`
+	SSHURL      string `yaml:"ssh_url"`
+	Owner       string `yaml:"owner"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Branch      string `yaml:"branch"`
+}
+
+// manifestFields lists the keys accepted for a repository entry
+var manifestFields = map[string]bool{
+	"clone_url":   true,
+	"ssh_url":     true,
+	"owner":       true,
+	"name":        true,
+	"description": true,
+	"branch":      true,
+}
+
+// NewManifestClient creates a new manifest client reading the given YAML or JSON file
+func NewManifestClient(path string) (*ManifestClient, error) {
+	if path == "" {
+		return nil, fmt.Errorf("a manifest file is required for the manifest source (use --manifest)")
+	}
+
+	return &ManifestClient{
+		path: path,
+	}, nil
+}
+
+// GetName returns the source name
+func (m *ManifestClient) GetName() string {
+	return "manifest"
+}
+
+// GetRepositories reads all repositories listed in the manifest file.
+// The organization is not used, the manifest defines the complete set.
+func (m *ManifestClient) GetRepositories(ctx context.Context, organization string) ([]types.Repository, error) {
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	return Parse(m.path, data)
+}
+
+// Parse parses a manifest in YAML or JSON format. The manifest is either a
+// list of repositories or a mapping with a "repositories" list. Validation
+// errors are reported as name:line: message.
+func Parse(name string, data []byte) ([]types.Repository, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if len(document.Content) == 0 {
+		return nil, fmt.Errorf("%s: manifest is empty", name)
+	}
+
+	list := document.Content[0]
+	if list.Kind == yaml.MappingNode {
+		list = lookup(list, "repositories")
+		if list == nil {
+			return nil, fmt.Errorf("%s:%d: manifest has no repositories list", name, document.Content[0].Line)
+		}
+	}
+
+	if list.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("%s:%d: repositories must be a list", name, list.Line)
+	}
+
+	var repos []types.Repository
+	seen := make(map[string]int)
+
+	for _, item := range list.Content {
+		repo, err := parseEntry(item)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, item.Line, err)
+		}
+
+		key := repo.Owner + "/" + repo.Name
+		if line, ok := seen[key]; ok {
+			return nil, fmt.Errorf("%s:%d: duplicate repository %s (first listed on line %d)", name, item.Line, key, line)
+		}
+		seen[key] = item.Line
+
+		repos = append(repos, repo)
+	}
+
+	return repos, nil
+}
+
+// parseEntry validates a single repository entry and converts it to a Repository
+func parseEntry(item *yaml.Node) (types.Repository, error) {
+	if item.Kind != yaml.MappingNode {
+		return types.Repository{}, fmt.Errorf("repository entry must be a mapping")
+	}
+
+	for i := 0; i < len(item.Content); i += 2 {
+		if key := item.Content[i]; !manifestFields[key.Value] {
+			return types.Repository{}, fmt.Errorf("unknown field %q on line %d", key.Value, key.Line)
+		}
+	}
+
+	var entry ManifestEntry
+	if err := item.Decode(&entry); err != nil {
+		return types.Repository{}, err
+	}
+
+	if entry.CloneURL == "" {
+		return types.Repository{}, fmt.Errorf("repository entry is missing clone_url")
+	}
+
+	// Owner and name default to the path of the clone URL
+	if entry.Owner == "" || entry.Name == "" {
+		r, err := remote.Parse(entry.CloneURL)
+		if err != nil {
+			return types.Repository{}, err
+		}
+		if entry.Owner == "" {
+			entry.Owner = r.Owner
+		}
+		if entry.Name == "" {
+			entry.Name = r.Name
+		}
+	}
+
+	if entry.Owner == "" {
+		return types.Repository{}, fmt.Errorf("cannot determine owner from clone_url %q, set owner explicitly", entry.CloneURL)
+	}
+
+	if strings.Contains(entry.Name, "/") || entry.Name == "." || entry.Name == ".." {
+		return types.Repository{}, fmt.Errorf("invalid repository name %q", entry.Name)
+	}
+
+	return types.Repository{
+		Name:        entry.Name,
+		FullName:    entry.Owner + "/" + entry.Name,
+		CloneURL:    entry.CloneURL,
+		SSHURL:      entry.SSHURL,
+		Description: entry.Description,
+		Owner:       entry.Owner,
+		Branch:      entry.Branch,
+	}, nil
+}
+
+// lookup returns the value node for key in a mapping node
+func lookup(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	data := `repositories:
+  - clone_url: https://github.com/acme/api.git
+    branch: release
+  - clone_url: git@bitbucket.org:jdoe/tools.git
+    owner: tooling
+    description: Internal tools
+`
+
+	repos, err := Parse("manifest.yaml", []byte(data))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(repos) != 2 {
+		t.Fatalf("Expected 2 repositories, got %d", len(repos))
+	}
+
+	if repos[0].Owner != "acme" || repos[0].Name != "api" || repos[0].Branch != "release" {
+		t.Errorf("Unexpected first repository %+v", repos[0])
+	}
+
+	if repos[1].Owner != "tooling" || repos[1].Name != "tools" || repos[1].FullName != "tooling/tools" {
+		t.Errorf("Unexpected second repository %+v", repos[1])
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	data := `[{"clone_url": "https://gitlab.com/acme/platform/api.git", "ssh_url": "git@gitlab.com:acme/platform/api.git"}]`
+
+	repos, err := Parse("manifest.json", []byte(data))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(repos) != 1 || repos[0].Owner != "acme/platform" || repos[0].SSHURL == "" {
+		t.Errorf("Unexpected repositories %+v", repos)
+	}
+}
+
+func TestParseValidationErrors(t *testing.T) {
+	tests := map[string]string{
+		"missing clone_url": "repositories:\n  - clone_url: https://github.com/acme/api.git\n  - name: web\n",
+		"unknown field":     "repositories:\n  - clone_url: https://github.com/acme/api.git\n    brnch: main\n",
+		"duplicate":         "- clone_url: https://github.com/acme/api.git\n- clone_url: git@github.com:acme/api.git\n",
+	}
+	expected := map[string]string{
+		"missing clone_url": "manifest.yaml:3:",
+		"unknown field":     "on line 3",
+		"duplicate":         "manifest.yaml:2: duplicate repository acme/api (first listed on line 1)",
+	}
+
+	for name, data := range tests {
+		_, err := Parse("manifest.yaml", []byte(data))
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+			continue
+		}
+		if !strings.Contains(err.Error(), expected[name]) {
+			t.Errorf("%s: expected error containing '%s', got '%v'", name, expected[name], err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jonasbn/baseline/internal/git"
+)
+
+// Store keeps a file per repository of the baseline, describing the files in the tree
+// of a commit, in a directory below the metadata directory of the baseline
+type Store struct {
+	// Dir is the directory below the metadata directory
+	Dir string
+	// Ext is the extension of the files
+	Ext string
+	// Name describes the files in errors, e.g. index or symbols
+	Name string
+}
+
+// Table is the data kept for a repository, updated file by file as its HEAD moves
+type Table interface {
+	// Commit returns the commit described, "" for a new table
+	Commit() string
+	// Update replaces the data of the files with the files of commit, files missing
+	// from its tree are removed
+	Update(ctx context.Context, repoPath, commit string, names []string) error
+	// Encode returns the content of the stored file
+	Encode() ([]byte, error)
+}
+
+// Path returns the path of the file of the repository stored at repoPath in the baseline root
+func (s Store) Path(root, repoPath string) (string, error) {
+	rel, err := filepath.Rel(root, repoPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, git.MetadataDir, s.Dir, rel+s.Ext), nil
+}
+
+// Exists reports whether the baseline root has the store directory
+func (s Store) Exists(root string) bool {
+	info, err := os.Stat(filepath.Join(root, git.MetadataDir, s.Dir))
+	return err == nil && info.IsDir()
+}
+
+// Write replaces the file of the repository stored at repoPath atomically
+func (s Store) Write(root, repoPath string, data []byte) error {
+	path, err := s.Path(root, repoPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s directory: %w", s.Name, err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s %s: %w", s.Name, path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s %s: %w", s.Name, path, err)
+	}
+
+	return nil
+}
+
+// Remove deletes the file of the repository stored at repoPath, if any
+func (s Store) Remove(root, repoPath string) error {
+	path, err := s.Path(root, repoPath)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Move moves the file of a repository moved from oldPath to newPath, if any
+func (s Store) Move(root, oldPath, newPath string) error {
+	from, err := s.Path(root, oldPath)
+	if err != nil {
+		return err
+	}
+	to, err := s.Path(root, newPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(from); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return fmt.Errorf("failed to create %s directory: %w", s.Name, err)
+	}
+	return os.Rename(from, to)
+}
+
+// Refresh brings the table of the repository stored at repoPath up to date with its
+// HEAD. The table returned by load, nil or os.ErrNotExist when there is none, is
+// updated with the files changed since its commit; otherwise, or if that commit is no
+// longer available, the table returned by create is filled from scratch. It reports
+// whether the table was rebuilt and the number of files read again.
+func (s Store) Refresh(ctx context.Context, root, repoPath string, load func() (Table, error), create func() Table) (bool, int, error) {
+	head, err := git.ResolveCommit(repoPath, "HEAD")
+	if err != nil {
+		// An empty repository has no files
+		return false, 0, s.Remove(root, repoPath)
+	}
+
+	table, err := load()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, 0, err
+	}
+
+	var names []string
+	rebuilt := err != nil || table == nil
+	if !rebuilt {
+		if table.Commit() == head {
+			return false, 0, nil
+		}
+		names, err = git.ChangedFiles(repoPath, table.Commit(), head)
+		if err != nil {
+			// The commit of the table is gone, e.g. after a force push
+			rebuilt = true
+		}
+	}
+
+	if rebuilt {
+		table = create()
+		names, err = git.ListTree(repoPath, head)
+		if err != nil {
+			return true, 0, err
+		}
+	}
+
+	if err := table.Update(ctx, repoPath, head, names); err != nil {
+		return rebuilt, 0, err
+	}
+	data, err := table.Encode()
+	if err != nil {
+		return rebuilt, 0, fmt.Errorf("failed to encode %s: %w", s.Name, err)
+	}
+
+	return rebuilt, len(names), s.Write(root, repoPath, data)
+}
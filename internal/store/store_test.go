@@ -0,0 +1,51 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	root := t.TempDir()
+	s := Store{Dir: "things", Ext: ".thing", Name: "things"}
+	repoPath := filepath.Join(root, "acme", "api")
+
+	path, err := s.Path(root, repoPath)
+	if err != nil || path != filepath.Join(root, ".baseline", "things", "acme", "api.thing") {
+		t.Fatalf("Unexpected path %s (%v)", path, err)
+	}
+	if s.Exists(root) {
+		t.Error("Expected no store before the first write")
+	}
+
+	if err := s.Write(root, repoPath, []byte("data")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "data" {
+		t.Errorf("Expected the written data, got %q (%v)", content, err)
+	}
+	if !s.Exists(root) {
+		t.Error("Expected the store to exist")
+	}
+
+	newPath := filepath.Join(root, "acme", "service")
+	if err := s.Move(root, repoPath, newPath); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	moved, _ := s.Path(root, newPath)
+	if _, err := os.Stat(moved); err != nil {
+		t.Errorf("Expected the file at the new path: %v", err)
+	}
+	// Repositories without a file are moved without error
+	if err := s.Move(root, repoPath, newPath); err != nil {
+		t.Errorf("Move of a missing file failed: %v", err)
+	}
+
+	if err := s.Remove(root, newPath); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := s.Remove(root, newPath); err != nil {
+		t.Errorf("Remove of a missing file failed: %v", err)
+	}
+}
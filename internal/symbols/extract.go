@@ -0,0 +1,273 @@
+package symbols
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jonasbn/baseline/internal/git"
+	"github.com/jonasbn/baseline/internal/search"
+)
+
+const (
+	// ToolCtags extracts the symbols of every language universal-ctags knows
+	ToolCtags = "ctags"
+	// ToolGo extracts the symbols of Go files with the built-in parser
+	ToolGo = "go"
+)
+
+// ctagsBatch is the number of files handed to one ctags process
+const ctagsBatch = 2000
+
+// ctagsPath returns the path of universal-ctags with JSON output, or "" when it is not
+// available. Exuberant ctags and other implementations lack the JSON output.
+var ctagsPath = sync.OnceValue(func() string {
+	path, err := exec.LookPath("ctags")
+	if err != nil {
+		return ""
+	}
+	output, err := exec.Command(path, "--version").Output()
+	if err != nil || !bytes.Contains(output, []byte("Universal Ctags")) || !bytes.Contains(output, []byte("+json")) {
+		return ""
+	}
+	return path
+})
+
+// Tool returns the extractor used on this machine: universal-ctags when installed, the
+// built-in Go parser otherwise
+func Tool() string {
+	if ctagsPath() != "" {
+		return ToolCtags
+	}
+	return ToolGo
+}
+
+// extract returns the symbols of the files of commit, files without symbols are left out
+func extract(ctx context.Context, tool, repoPath, commit string, names []string) (map[string][]Symbol, error) {
+	if tool == ToolCtags {
+		symbols := make(map[string][]Symbol)
+		for start := 0; start < len(names); start += ctagsBatch {
+			batch := names[start:min(start+ctagsBatch, len(names))]
+			if err := extractCtags(ctx, repoPath, commit, batch, symbols); err != nil {
+				return nil, err
+			}
+		}
+		return symbols, nil
+	}
+
+	var goFiles []string
+	for _, name := range names {
+		if search.Extension(name) == ".go" {
+			goFiles = append(goFiles, name)
+		}
+	}
+
+	symbols := make(map[string][]Symbol)
+	err := git.ReadBlobs(ctx, repoPath, commit, goFiles, func(name string, content []byte) {
+		if s := GoSymbols(name, content); len(s) > 0 {
+			symbols[name] = s
+		}
+	})
+	return symbols, err
+}
+
+// extractCtags writes the files to a temporary directory and runs ctags over them, ctags
+// reads files from disk and the tree of a mirror has none
+func extractCtags(ctx context.Context, repoPath, commit string, names []string, symbols map[string][]Symbol) error {
+	tmp, err := os.MkdirTemp("", "baseline-symbols-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	var written []string
+	var writeErr error
+	err = git.ReadBlobs(ctx, repoPath, commit, names, func(name string, content []byte) {
+		// ctags reads one name per line, and nothing may be written outside tmp
+		if writeErr != nil || search.IsBinary(content) || strings.Contains(name, "\n") || !filepath.IsLocal(name) {
+			return
+		}
+		file := filepath.Join(tmp, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			writeErr = err
+			return
+		}
+		if err := os.WriteFile(file, content, 0644); err != nil {
+			writeErr = err
+			return
+		}
+		// A leading "./" keeps names such as "-o" from being read as options
+		written = append(written, "./"+name)
+	})
+	if err != nil {
+		return err
+	}
+	if writeErr != nil {
+		return fmt.Errorf("failed to prepare files for ctags: %w", writeErr)
+	}
+	if len(written) == 0 {
+		return nil
+	}
+
+	cmd := exec.CommandContext(ctx, ctagsPath(), "--output-format=json", "--fields=+nKls", "--sort=no", "-f", "-", "-L", "-")
+	cmd.Dir = tmp
+	cmd.Stdin = strings.NewReader(strings.Join(written, "\n") + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run ctags: %w", err)
+	}
+
+	parseErr := parseCtags(stdout, symbols)
+	if parseErr != nil {
+		// Let ctags finish writing before waiting for it
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("ctags in %s: %w: %s", repoPath, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return parseErr
+}
+
+// ctagsTag is a tag printed by ctags --output-format=json
+type ctagsTag struct {
+	Type     string `json:"_type"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	Kind     string `json:"kind"`
+	Language string `json:"language"`
+	Scope    string `json:"scope"`
+}
+
+// parseCtags adds the tags of the JSON lines printed by ctags to symbols
+func parseCtags(r io.Reader, symbols map[string][]Symbol) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var tag ctagsTag
+		if err := json.Unmarshal(scanner.Bytes(), &tag); err != nil {
+			return fmt.Errorf("unexpected ctags output %q: %w", scanner.Text(), err)
+		}
+		// Pseudo tags describe the run, not the files
+		if tag.Type != "tag" || tag.Name == "" {
+			continue
+		}
+		name := strings.TrimPrefix(filepath.ToSlash(tag.Path), "./")
+		symbols[name] = append(symbols[name], Symbol{
+			Name:     tag.Name,
+			Kind:     tag.Kind,
+			Language: tag.Language,
+			Line:     tag.Line,
+			Scope:    tag.Scope,
+		})
+	}
+	return scanner.Err()
+}
+
+// GoSymbols returns the package level definitions of a Go file, with the fields of structs
+// and the methods of interfaces, named by the kinds universal-ctags uses for Go. Files with
+// syntax errors yield what could be parsed.
+func GoSymbols(name string, content []byte) []Symbol {
+	fset := token.NewFileSet()
+	file, _ := parser.ParseFile(fset, name, content, parser.SkipObjectResolution)
+	if file == nil {
+		return nil
+	}
+
+	var symbols []Symbol
+	add := func(ident *ast.Ident, kind, scope string) {
+		if ident == nil || ident.Name == "_" {
+			return
+		}
+		symbols = append(symbols, Symbol{
+			Name:     ident.Name,
+			Kind:     kind,
+			Language: "Go",
+			Line:     fset.Position(ident.Pos()).Line,
+			Scope:    scope,
+		})
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			scope := ""
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				scope = receiverType(d.Recv.List[0].Type)
+			}
+			add(d.Name, "func", scope)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					switch t := s.Type.(type) {
+					case *ast.StructType:
+						add(s.Name, "struct", "")
+						for _, field := range t.Fields.List {
+							for _, n := range field.Names {
+								add(n, "member", s.Name.Name)
+							}
+						}
+					case *ast.InterfaceType:
+						add(s.Name, "interface", "")
+						for _, method := range t.Methods.List {
+							for _, n := range method.Names {
+								add(n, "methodSpec", s.Name.Name)
+							}
+						}
+					default:
+						add(s.Name, "type", "")
+					}
+				case *ast.ValueSpec:
+					kind := "var"
+					if d.Tok == token.CONST {
+						kind = "const"
+					}
+					for _, n := range s.Names {
+						add(n, kind, "")
+					}
+				}
+			}
+		}
+	}
+
+	return symbols
+}
+
+// receiverType returns the name of the type of a method receiver, without pointer and
+// type parameters
+func receiverType(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
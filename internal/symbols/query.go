@@ -0,0 +1,109 @@
+package symbols
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/jonasbn/baseline/internal/search"
+)
+
+// Options selects the symbols returned by Find
+type Options struct {
+	// Name is the name of the symbol: an exact name, a glob such as '*Handler', or a
+	// regular expression prefixed with re:
+	Name       string
+	IgnoreCase bool
+	// Kinds limits the symbols to the kinds, e.g. func or struct
+	Kinds []string
+	// Types limits the symbols to files of the languages, see search.Languages
+	Types []string
+}
+
+// Query is a compiled set of options
+type Query struct {
+	name       string
+	glob       bool
+	re         *regexp.Regexp
+	ignoreCase bool
+	kinds      map[string]bool
+	extensions map[string]bool
+}
+
+// NewQuery validates the options and compiles the query
+func NewQuery(opts Options) (*Query, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("symbol name is required")
+	}
+
+	q := &Query{name: opts.Name, ignoreCase: opts.IgnoreCase}
+	switch {
+	case strings.HasPrefix(opts.Name, "re:"):
+		expr := strings.TrimPrefix(opts.Name, "re:")
+		if opts.IgnoreCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid symbol pattern %q: %w", opts.Name, err)
+		}
+		q.re = re
+	case strings.ContainsAny(opts.Name, "*?["):
+		if _, err := path.Match(opts.Name, ""); err != nil {
+			return nil, fmt.Errorf("invalid symbol pattern %q: %w", opts.Name, err)
+		}
+		q.glob = true
+	}
+	if opts.IgnoreCase && q.re == nil {
+		q.name = strings.ToLower(q.name)
+	}
+
+	if len(opts.Kinds) > 0 {
+		q.kinds = make(map[string]bool)
+		for _, k := range opts.Kinds {
+			q.kinds[strings.ToLower(k)] = true
+		}
+	}
+
+	if len(opts.Types) > 0 {
+		q.extensions = make(map[string]bool)
+		for _, t := range opts.Types {
+			extensions, ok := search.Languages[strings.ToLower(t)]
+			if !ok {
+				return nil, fmt.Errorf("unknown type %q, known types: %s", t, strings.Join(search.LanguageNames(), ", "))
+			}
+			for _, ext := range extensions {
+				q.extensions[ext] = true
+			}
+		}
+	}
+
+	return q, nil
+}
+
+// selectsFile reports whether the symbols of the file are considered
+func (q *Query) selectsFile(name string) bool {
+	return q.extensions == nil || q.extensions[search.Extension(name)]
+}
+
+// matches reports whether the symbol has a matching name and kind
+func (q *Query) matches(s Symbol) bool {
+	if q.kinds != nil && !q.kinds[strings.ToLower(s.Kind)] {
+		return false
+	}
+
+	if q.re != nil {
+		return q.re.MatchString(s.Name)
+	}
+
+	name := s.Name
+	if q.ignoreCase {
+		name = strings.ToLower(name)
+	}
+	if q.glob {
+		matched, _ := path.Match(q.name, name)
+		return matched
+	}
+	return name == q.name
+}
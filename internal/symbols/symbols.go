@@ -0,0 +1,187 @@
+package symbols
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/jonasbn/baseline/internal/store"
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+// Dir is the directory below the metadata directory of the baseline holding the symbol
+// database, one table per repository
+const Dir = "symbols"
+
+// Store keeps the symbol table of each repository of the baseline
+var Store = store.Store{Dir: Dir, Ext: ".sym", Name: "symbols"}
+
+// formatVersion is increased whenever the stored format changes, older tables are rebuilt
+const formatVersion = 1
+
+// Symbol is a definition found in a file
+type Symbol struct {
+	Name string
+	// Kind is the kind of definition as named by universal-ctags, e.g. func, struct or member
+	Kind     string
+	Language string
+	Line     int
+	// Scope is the enclosing definition, e.g. the type of a method or field
+	Scope string
+}
+
+// Definition is a symbol and the file defining it
+type Definition struct {
+	Path string
+	Symbol
+}
+
+// Table holds the symbols defined in the tree of a commit
+type Table struct {
+	Version int
+	Commit  string
+	// Tool is the extractor the symbols were taken with, a table built by another is rebuilt
+	Tool string
+	// Files maps the path of each file to its symbols, files without symbols are left out
+	Files map[string][]Symbol
+}
+
+// Result is the outcome of refreshing the symbols of a repository
+type Result struct {
+	Repository types.Repository
+	// Rebuilt is set when the table was built from scratch instead of updated
+	Rebuilt bool
+	// Changed is the number of files read again
+	Changed  int
+	Tool     string
+	Error    error
+	Duration time.Duration
+}
+
+// Load reads the symbol table of the repository stored at repoPath. A missing table, or
+// one in an older format, is reported as os.ErrNotExist.
+func Load(root, repoPath string) (*Table, error) {
+	path, err := Store.Path(root, repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var table Table
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&table); err != nil {
+		return nil, fmt.Errorf("failed to read symbols %s: %w", path, err)
+	}
+	if table.Version != formatVersion {
+		return nil, os.ErrNotExist
+	}
+
+	return &table, nil
+}
+
+// tableUpdate updates a symbol table with the extractor of the run
+type tableUpdate struct {
+	*Table
+	tool string
+}
+
+func (t *tableUpdate) Commit() string {
+	return t.Table.Commit
+}
+
+func (t *tableUpdate) Update(ctx context.Context, repoPath, commit string, names []string) error {
+	// Deleted files are missing from the tree and stay removed
+	for _, name := range names {
+		delete(t.Files, name)
+	}
+	extracted, err := extract(ctx, t.tool, repoPath, commit, names)
+	if err != nil {
+		return err
+	}
+	for name, symbols := range extracted {
+		t.Files[name] = symbols
+	}
+
+	t.Version = formatVersion
+	t.Table.Commit = commit
+	t.Tool = t.tool
+	return nil
+}
+
+func (t *tableUpdate) Encode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(t.Table); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Refresh brings the symbol table of the repository stored at its BaselinePath up to date
+// with its HEAD. An existing table is updated with the files changed since its commit,
+// otherwise, or if that commit is no longer available or the extractor changed, it is
+// built from scratch.
+func Refresh(ctx context.Context, root string, repo types.Repository) Result {
+	start := time.Now()
+	result := Result{Repository: repo, Tool: Tool()}
+
+	result.Rebuilt, result.Changed, result.Error = refresh(ctx, root, repo.BaselinePath, result.Tool)
+	result.Duration = time.Since(start)
+
+	return result
+}
+
+func refresh(ctx context.Context, root, repoPath, tool string) (bool, int, error) {
+	load := func() (store.Table, error) {
+		table, err := Load(root, repoPath)
+		if err != nil {
+			return nil, err
+		}
+		if table.Tool != tool {
+			// Symbols of another extractor are taken again from every file
+			return nil, nil
+		}
+		return &tableUpdate{Table: table, tool: tool}, nil
+	}
+	create := func() store.Table {
+		return &tableUpdate{Table: &Table{Files: make(map[string][]Symbol)}, tool: tool}
+	}
+	return Store.Refresh(ctx, root, repoPath, load, create)
+}
+
+// Find returns the definitions in the symbol table of the repository stored at repoPath
+// matching the query, sorted by path and line. A repository without a table is reported
+// as os.ErrNotExist.
+func Find(root, repoPath string, q *Query) ([]Definition, error) {
+	table, err := Load(root, repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var definitions []Definition
+	for name, symbols := range table.Files {
+		if !q.selectsFile(name) {
+			continue
+		}
+		for _, s := range symbols {
+			if q.matches(s) {
+				definitions = append(definitions, Definition{Path: name, Symbol: s})
+			}
+		}
+	}
+
+	sort.Slice(definitions, func(i, j int) bool {
+		if definitions[i].Path != definitions[j].Path {
+			return definitions[i].Path < definitions[j].Path
+		}
+		return definitions[i].Line < definitions[j].Line
+	})
+
+	return definitions, nil
+}
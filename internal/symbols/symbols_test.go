@@ -0,0 +1,230 @@
+package symbols
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jonasbn/baseline/internal/testutil"
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+const goSource = `package server
+
+const Version = "1.0"
+
+var (
+	defaultPort = 8080
+	_           = 1
+)
+
+type Handler interface {
+	ServeFoo(w Writer) error
+}
+
+type FooHandler struct {
+	Name, Path string
+}
+
+type ID string
+
+func (h *FooHandler) ServeFoo(w Writer) error { return nil }
+
+func (List[T]) Len() int { return 0 }
+
+func NewFooHandler() *FooHandler { return nil }
+`
+
+func TestGoSymbols(t *testing.T) {
+	expected := []Symbol{
+		{"Version", "const", "Go", 3, ""},
+		{"defaultPort", "var", "Go", 6, ""},
+		{"Handler", "interface", "Go", 10, ""},
+		{"ServeFoo", "methodSpec", "Go", 11, "Handler"},
+		{"FooHandler", "struct", "Go", 14, ""},
+		{"Name", "member", "Go", 15, "FooHandler"},
+		{"Path", "member", "Go", 15, "FooHandler"},
+		{"ID", "type", "Go", 18, ""},
+		{"ServeFoo", "func", "Go", 20, "FooHandler"},
+		{"Len", "func", "Go", 22, "List"},
+		{"NewFooHandler", "func", "Go", 24, ""},
+	}
+
+	if got := GoSymbols("server.go", []byte(goSource)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	// What parses before a syntax error is kept
+	got := GoSymbols("broken.go", []byte("package broken\n\nfunc Good() {}\n\nfunc Bad( {\n"))
+	if len(got) == 0 || got[0].Name != "Good" {
+		t.Errorf("Expected Good from the broken file, got %v", got)
+	}
+}
+
+func TestParseCtags(t *testing.T) {
+	output := `{"_type": "ptag", "name": "JSON_OUTPUT_VERSION", "path": "0.0"}
+{"_type": "tag", "name": "Client", "path": "lib/client.py", "pattern": "/^class Client:$/", "line": 3, "kind": "class", "language": "Python"}
+{"_type": "tag", "name": "connect", "path": "lib/client.py", "pattern": "/^    def connect(self):$/", "line": 5, "kind": "member", "language": "Python", "scope": "Client", "scopeKind": "class"}
+`
+	symbols := make(map[string][]Symbol)
+	if err := parseCtags(strings.NewReader(output), symbols); err != nil {
+		t.Fatalf("parseCtags failed: %v", err)
+	}
+
+	expected := map[string][]Symbol{
+		"lib/client.py": {
+			{"Client", "class", "Python", 3, ""},
+			{"connect", "member", "Python", 5, "Client"},
+		},
+	}
+	if !reflect.DeepEqual(symbols, expected) {
+		t.Errorf("Expected %v, got %v", expected, symbols)
+	}
+
+	if err := parseCtags(strings.NewReader("not json\n"), symbols); err == nil {
+		t.Error("Expected an error for invalid output")
+	}
+}
+
+// fakeCtags prints a tag for every file listed on stdin, and fails for names that do
+// not start with "./" and could be taken for options
+const fakeCtags = `#!/bin/sh
+while IFS= read -r f; do
+	case "$f" in ./*) ;; *) echo "unexpected name $f" >&2; exit 1 ;; esac
+	printf '{"_type": "tag", "name": "main", "path": "%s", "line": 1, "kind": "function", "language": "Sh"}\n' "$f"
+done
+`
+
+func TestExtractCtags(t *testing.T) {
+	testutil.SetIdentity(t)
+
+	ctags := filepath.Join(t.TempDir(), "ctags")
+	if err := os.WriteFile(ctags, []byte(fakeCtags), 0755); err != nil {
+		t.Fatal(err)
+	}
+	saved := ctagsPath
+	ctagsPath = func() string { return ctags }
+	t.Cleanup(func() { ctagsPath = saved })
+
+	dir := t.TempDir()
+	testutil.InitRepository(t, dir, "")
+	testutil.CommitFiles(t, dir, map[string]string{"-o": "#!/bin/sh\n", "run.sh": "#!/bin/sh\n"})
+	commit := testutil.Git(t, dir, "rev-parse", "HEAD")
+
+	symbols, err := extract(context.Background(), ToolCtags, dir, commit, []string{"-o", "run.sh"})
+	if err != nil {
+		t.Fatalf("extract failed: %v", err)
+	}
+	if len(symbols["-o"]) != 1 || len(symbols["run.sh"]) != 1 {
+		t.Errorf("Expected a symbol for each file, got %v", symbols)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	handler := Symbol{Name: "FooHandler", Kind: "struct", Language: "Go"}
+	constructor := Symbol{Name: "NewFooHandler", Kind: "func", Language: "Go"}
+
+	tests := []struct {
+		opts     Options
+		symbol   Symbol
+		expected bool
+	}{
+		{Options{Name: "FooHandler"}, handler, true},
+		{Options{Name: "fooHandler"}, handler, false},
+		{Options{Name: "fooHandler", IgnoreCase: true}, handler, true},
+		{Options{Name: "Foo"}, handler, false},
+		{Options{Name: "*Handler"}, constructor, true},
+		{Options{Name: "*handler", IgnoreCase: true}, constructor, true},
+		{Options{Name: "re:^New"}, constructor, true},
+		{Options{Name: "re:^new", IgnoreCase: true}, constructor, true},
+		{Options{Name: "re:^New"}, handler, false},
+		{Options{Name: "*Handler", Kinds: []string{"func"}}, handler, false},
+		{Options{Name: "*Handler", Kinds: []string{"FUNC"}}, constructor, true},
+	}
+
+	for _, tt := range tests {
+		q, err := NewQuery(tt.opts)
+		if err != nil {
+			t.Fatalf("NewQuery(%+v) failed: %v", tt.opts, err)
+		}
+		if got := q.matches(tt.symbol); got != tt.expected {
+			t.Errorf("%+v matching %s: expected %v, got %v", tt.opts, tt.symbol.Name, tt.expected, got)
+		}
+	}
+
+	for _, opts := range []Options{{}, {Name: "re:("}, {Name: "[a"}, {Name: "x", Types: []string{"cobol"}}} {
+		if _, err := NewQuery(opts); err == nil {
+			t.Errorf("Expected an error for %+v", opts)
+		}
+	}
+}
+
+func TestRefreshAndFind(t *testing.T) {
+	// Use the Go parser whether or not ctags is installed
+	ctagsPath = func() string { return "" }
+
+	testutil.SetIdentity(t)
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "acme", "api")
+	testutil.InitRepository(t, dir, "")
+	testutil.CommitFiles(t, dir, map[string]string{
+		"server.go": goSource,
+		"util.go":   "package server\n\nfunc helper() {}\n",
+		"README.md": "# FooHandler\n",
+	})
+	repo := types.Repository{FullName: "acme/api", BaselinePath: dir}
+
+	q, err := NewQuery(Options{Name: "*Handler", Types: []string{"go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Find(root, dir, q); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected os.ErrNotExist before refreshing, got %v", err)
+	}
+
+	result := Refresh(context.Background(), root, repo)
+	if result.Error != nil || !result.Rebuilt || result.Changed != 3 || result.Tool != ToolGo {
+		t.Fatalf("Unexpected first refresh %+v", result)
+	}
+	if !Store.Exists(root) {
+		t.Error("Expected the symbol database to exist")
+	}
+
+	definitions, err := Find(root, dir, q)
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	var names []string
+	for _, d := range definitions {
+		names = append(names, d.Path+":"+d.Name)
+	}
+	expected := []string{"server.go:Handler", "server.go:FooHandler", "server.go:NewFooHandler"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+
+	// Only the changed file is read again, the deleted one drops its symbols
+	testutil.Git(t, dir, "rm", "-q", "server.go")
+	testutil.CommitFiles(t, dir, map[string]string{"util.go": "package server\n\ntype BarHandler struct{}\n"})
+
+	result = Refresh(context.Background(), root, repo)
+	if result.Error != nil || result.Rebuilt || result.Changed != 2 {
+		t.Fatalf("Unexpected second refresh %+v", result)
+	}
+	definitions, err = Find(root, dir, q)
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(definitions) != 1 || definitions[0].Name != "BarHandler" || definitions[0].Line != 3 {
+		t.Errorf("Expected BarHandler only, got %+v", definitions)
+	}
+
+	if result := Refresh(context.Background(), root, repo); result.Error != nil || result.Changed != 0 {
+		t.Errorf("Expected an unchanged table, got %+v", result)
+	}
+}
@@ -7,16 +7,28 @@ import (
 
 // Repository represents a Git repository with its metadata
 type Repository struct {
-	Name        string    `json:"name"`
-	FullName    string    `json:"full_name"`
-	CloneURL    string    `json:"clone_url"`
-	SSHURL      string    `json:"ssh_url"`
-	HTTPSURL    string    `json:"https_url"`
-	Description string    `json:"description"`
-	Private     bool      `json:"private"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Language    string    `json:"language"`
-	Owner       string    `json:"owner"`
+	ID            string    `json:"id"` // stable identifier assigned by the source, kept across renames and transfers
+	Name          string    `json:"name"`
+	FullName      string    `json:"full_name"`
+	CloneURL      string    `json:"clone_url"`
+	SSHURL        string    `json:"ssh_url"`
+	HTTPSURL      string    `json:"https_url"`
+	Description   string    `json:"description"`
+	Private       bool      `json:"private"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Language      string    `json:"language"`
+	Owner         string    `json:"owner"`
+	DefaultBranch string    `json:"default_branch"`
+	Disabled      bool      `json:"disabled"`   // true if the source has disabled access to the repository
+	Archived      bool      `json:"archived"`   // true if the repository is archived (read-only) in the source
+	Fork          bool      `json:"fork"`       // true if the repository is a fork of another repository
+	Size          int64     `json:"size"`       // size reported by the source in kilobytes, 0 if unknown
+	Empty         bool      `json:"empty"`      // true if the repository has no commits
+	Topics        []string  `json:"topics"`     // topics or tags assigned in the source
+	Branch        string    `json:"branch"`     // branch to check out instead of the default branch
+	LocalPath     string    `json:"local_path"` // existing local clone to borrow objects from when cloning
+	Source        string    `json:"source"`     // name of the source the repository was fetched from
+	BaselinePath  string    `json:"-"`          // directory of the clone in the baseline, set by commands working on it
 }
 
 // RepositorySource defines the interface for fetching repositories from different sources
@@ -47,23 +59,48 @@ type Credentials struct {
 type SourceType string
 
 const (
-	SourceGitHub    SourceType = "github"
-	SourceBitbucket SourceType = "bitbucket"
+	SourceGitHub          SourceType = "github"
+	SourceBitbucket       SourceType = "bitbucket"
+	SourceBitbucketServer SourceType = "bitbucket-server"
+	SourceGitLab          SourceType = "gitlab"
+	SourceGitea           SourceType = "gitea"
 )
 
 // CloneResult represents the result of a clone operation
 type CloneResult struct {
 	Repository Repository
 	Success    bool
+	Skipped    bool // true if the repository already existed and was not cloned
 	Error      error
 	Duration   time.Duration
+	// SubmoduleError is set if the repository was cloned but its submodules failed
+	SubmoduleError error
+	// LFSError is set if the repository was cloned but downloading Git LFS content failed
+	LFSError error
 }
 
+// UpdateState describes the state of the checked-out branch after an update
+type UpdateState string
+
+const (
+	UpdateStateUpToDate      UpdateState = "up-to-date"     // the branch already matched its upstream
+	UpdateStateFastForwarded UpdateState = "fast-forwarded" // the branch was advanced to its upstream
+	UpdateStateAhead         UpdateState = "ahead"          // the branch has commits not in its upstream
+	UpdateStateDiverged      UpdateState = "diverged"       // the branch and its upstream have diverged
+	UpdateStateDetached      UpdateState = "detached"       // HEAD is not on a branch
+	UpdateStateNoUpstream    UpdateState = "no-upstream"    // the branch has no upstream to follow
+)
+
 // UpdateResult represents the result of an update operation
 type UpdateResult struct {
 	Repository Repository
 	Success    bool
 	Error      error
 	Duration   time.Duration
-	Updated    bool // true if the repository was actually updated
+	Updated    bool        // true if the repository was actually updated
+	State      UpdateState // state of the checked-out branch after the update
+	// SubmoduleError is set if the repository was updated but its submodules failed
+	SubmoduleError error
+	// LFSError is set if the repository was updated but downloading Git LFS content failed
+	LFSError error
 }
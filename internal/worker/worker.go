@@ -5,81 +5,155 @@ import (
 	"sync"
 
 	"github.com/jonasbn/baseline/internal/git"
+	"github.com/jonasbn/baseline/internal/index"
+	"github.com/jonasbn/baseline/internal/search"
+	"github.com/jonasbn/baseline/internal/symbols"
 	"github.com/jonasbn/baseline/internal/types"
 )
 
 // WorkerPool manages concurrent Git operations
 type WorkerPool struct {
-	numWorkers int
-	gitOps     *git.GitOps
+	numWorkers  int
+	gitOps      *git.GitOps
+	convertMode bool
 }
 
 // NewWorkerPool creates a new worker pool
 func NewWorkerPool(numWorkers int, verbose bool) *WorkerPool {
+	return NewWorkerPoolWithOptions(numWorkers, verbose, git.Options{})
+}
+
+// NewWorkerPoolWithOptions creates a new worker pool using the given Git options
+func NewWorkerPoolWithOptions(numWorkers int, verbose bool, opts git.Options) *WorkerPool {
 	return &WorkerPool{
-		numWorkers: numWorkers,
-		gitOps:     git.NewGitOps(verbose),
+		numWorkers:  numWorkers,
+		gitOps:      git.NewGitOpsWithOptions(verbose, opts),
+		convertMode: opts.ConvertMode,
 	}
 }
 
 // CloneRepositories clones repositories concurrently
 func (wp *WorkerPool) CloneRepositories(ctx context.Context, repositories []types.Repository, targetDir string) <-chan types.CloneResult {
-	resultChan := make(chan types.CloneResult, len(repositories))
-	repoChan := make(chan types.Repository, len(repositories))
+	return run(ctx, wp.numWorkers, repositories, func(repo types.Repository) types.CloneResult {
+		return wp.cloneRepository(repo, targetDir)
+	})
+}
 
-	// Send all repositories to the channel
-	go func() {
-		defer close(repoChan)
-		for _, repo := range repositories {
-			select {
-			case repoChan <- repo:
-			case <-ctx.Done():
-				return
+// cloneRepository clones a repository, existing clones are skipped
+func (wp *WorkerPool) cloneRepository(repo types.Repository, targetDir string) types.CloneResult {
+	// Skip if repository already exists, unless the directory
+	// holds a different repository mapped to the same path
+	if wp.gitOps.RepositoryExists(repo, targetDir) {
+		repoPath := wp.gitOps.RepositoryPath(repo, targetDir)
+		result := types.CloneResult{
+			Repository: repo,
+			Success:    true,
+			Skipped:    true,
+			Error:      nil,
+			Duration:   0,
+		}
+		if err := wp.gitOps.CheckOrigin(repo, repoPath); err != nil {
+			result.Success = false
+			result.Skipped = false
+			result.Error = err
+		} else if err := wp.gitOps.CheckMode(repoPath); err != nil {
+			// A repository stored in the other mode is only replaced on request
+			if wp.convertMode {
+				result = wp.gitOps.ConvertRepository(repo, targetDir)
+			} else {
+				result.Success = false
+				result.Skipped = false
+				result.Error = err
 			}
 		}
-	}()
+		return result
+	}
 
-	// Start workers
-	var wg sync.WaitGroup
-	for i := 0; i < wp.numWorkers; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for repo := range repoChan {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-					// Skip if repository already exists
-					if wp.gitOps.RepositoryExists(repo, targetDir) {
-						resultChan <- types.CloneResult{
-							Repository: repo,
-							Success:    true,
-							Error:      nil,
-							Duration:   0,
-						}
-						continue
-					}
-
-					result := wp.gitOps.CloneRepository(repo, targetDir)
-					resultChan <- result
-				}
+	return wp.gitOps.CloneRepository(repo, targetDir)
+}
+
+// UpdateRepositories updates repositories concurrently
+func (wp *WorkerPool) UpdateRepositories(ctx context.Context, repositories []types.Repository, targetDir string) <-chan types.UpdateResult {
+	return run(ctx, wp.numWorkers, repositories, func(repo types.Repository) types.UpdateResult {
+		return wp.updateRepository(repo, targetDir)
+	})
+}
+
+// updateRepository updates an existing clone of a repository
+func (wp *WorkerPool) updateRepository(repo types.Repository, targetDir string) types.UpdateResult {
+	// Skip if repository doesn't exist
+	if !wp.gitOps.RepositoryExists(repo, targetDir) {
+		return types.UpdateResult{
+			Repository: repo,
+			Success:    false,
+			Updated:    false,
+			Error:      nil,
+			Duration:   0,
+		}
+	}
+
+	// Refuse to update a different repository mapped to the same path
+	repoPath := wp.gitOps.RepositoryPath(repo, targetDir)
+	if err := wp.gitOps.CheckOrigin(repo, repoPath); err != nil {
+		return types.UpdateResult{
+			Repository: repo,
+			Error:      err,
+		}
+	}
+
+	// A repository stored in the other mode is only replaced on request
+	if err := wp.gitOps.CheckMode(repoPath); err != nil {
+		if !wp.convertMode {
+			return types.UpdateResult{
+				Repository: repo,
+				Error:      err,
 			}
-		}()
+		}
+
+		converted := wp.gitOps.ConvertRepository(repo, targetDir)
+		return types.UpdateResult{
+			Repository: repo,
+			Success:    converted.Success,
+			Error:      converted.Error,
+			Duration:   converted.Duration,
+			Updated:    converted.Success,
+
+			SubmoduleError: converted.SubmoduleError,
+			LFSError:       converted.LFSError,
+		}
 	}
 
-	// Close result channel when all workers are done
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
+	return wp.gitOps.UpdateRepository(repo, targetDir)
+}
 
-	return resultChan
+// SearchRepositories searches the repositories concurrently. Each repository is
+// searched at its BaselinePath.
+func (wp *WorkerPool) SearchRepositories(ctx context.Context, repositories []types.Repository, searcher *search.Searcher) <-chan search.Result {
+	return run(ctx, wp.numWorkers, repositories, func(repo types.Repository) search.Result {
+		return searcher.Search(ctx, repo)
+	})
 }
 
-// UpdateRepositories updates repositories concurrently
-func (wp *WorkerPool) UpdateRepositories(ctx context.Context, repositories []types.Repository, targetDir string) <-chan types.UpdateResult {
-	resultChan := make(chan types.UpdateResult, len(repositories))
+// IndexRepositories refreshes the search indexes of the repositories concurrently. Each
+// repository is indexed at its BaselinePath, the indexes are stored below root.
+func (wp *WorkerPool) IndexRepositories(ctx context.Context, repositories []types.Repository, root string) <-chan index.Result {
+	return run(ctx, wp.numWorkers, repositories, func(repo types.Repository) index.Result {
+		return index.Refresh(ctx, root, repo)
+	})
+}
+
+// SymbolRepositories refreshes the symbol tables of the repositories concurrently. Each
+// repository is read at its BaselinePath, the tables are stored below root.
+func (wp *WorkerPool) SymbolRepositories(ctx context.Context, repositories []types.Repository, root string) <-chan symbols.Result {
+	return run(ctx, wp.numWorkers, repositories, func(repo types.Repository) symbols.Result {
+		return symbols.Refresh(ctx, root, repo)
+	})
+}
+
+// run calls fn for the repositories with numWorkers goroutines and sends the results
+// to the returned channel, which is closed when all are done or ctx is cancelled
+func run[R any](ctx context.Context, numWorkers int, repositories []types.Repository, fn func(types.Repository) R) <-chan R {
+	resultChan := make(chan R, len(repositories))
 	repoChan := make(chan types.Repository, len(repositories))
 
 	// Send all repositories to the channel
@@ -96,7 +170,7 @@ func (wp *WorkerPool) UpdateRepositories(ctx context.Context, repositories []typ
 
 	// Start workers
 	var wg sync.WaitGroup
-	for i := 0; i < wp.numWorkers; i++ {
+	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
@@ -105,20 +179,7 @@ func (wp *WorkerPool) UpdateRepositories(ctx context.Context, repositories []typ
 				case <-ctx.Done():
 					return
 				default:
-					// Skip if repository doesn't exist
-					if !wp.gitOps.RepositoryExists(repo, targetDir) {
-						resultChan <- types.UpdateResult{
-							Repository: repo,
-							Success:    false,
-							Updated:    false,
-							Error:      nil,
-							Duration:   0,
-						}
-						continue
-					}
-
-					result := wp.gitOps.UpdateRepository(repo, targetDir)
-					resultChan <- result
+					resultChan <- fn(repo)
 				}
 			}
 		}()
@@ -0,0 +1,31 @@
+package worker
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/jonasbn/baseline/internal/types"
+)
+
+func TestRun(t *testing.T) {
+	repositories := []types.Repository{{FullName: "acme/api"}, {FullName: "acme/web"}, {FullName: "other/docs"}}
+
+	var names []string
+	for name := range run(context.Background(), 2, repositories, func(repo types.Repository) string { return repo.FullName }) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) != 3 || names[0] != "acme/api" || names[2] != "other/docs" {
+		t.Errorf("Expected a result per repository, got %v", names)
+	}
+
+	// Nothing is run once the context is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for range run(ctx, 2, repositories, func(repo types.Repository) string {
+		t.Errorf("Unexpected run for %s", repo.FullName)
+		return ""
+	}) {
+	}
+}